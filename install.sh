@@ -36,6 +36,21 @@ downloadDatabases () {
 	rm -r $DIR
 }
 
+downloadITIS () {
+	# Downloads ITIS tables without importing them to MySQL
+	ITIS="https://www.itis.gov/downloads/itisMySQLBulk.zip"
+	mkdir -p $DIR
+	cd $DIR
+	echo "Downloading ITIS..."
+	wget $ITIS
+	echo "Extracting files..."
+	unzip itisMySQL*
+	mv itisMySQL*/* .
+	rm -r itisMySQL*
+	cd ../
+	echo "Upload with 'kestrel upload --itis $DIR'."
+}
+
 installSelenium () {
 	# Installs selenium package
 	echo "Installing Selenium driver..."
@@ -84,6 +99,7 @@ helpText () {
 	echo ""
 	echo "all	Installs all depenencies, including TensorFlow, Selenium package, and drivers."
 	echo "download Downloads taxonomy databases (takes several hours)"
+	echo "itis	Downloads ITIS files to the databases directory without importing them to MySQL."
 	echo "selenium Downloads newsest Selenium drivers."
 	echo "help	Prints help text and exits."
 	echo ""
@@ -103,6 +119,8 @@ elif [ $1 = "all" ]; then
 	installMain
 elif [ $1 = "download" ]; then
 	downloadDatabases
+elif [ $1 = "itis" ]; then
+	downloadITIS
 elif [ $1 = "selenium" ]; then
 	installSelenium
 elif [ $1 = "help" ]; then
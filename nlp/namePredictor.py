@@ -11,7 +11,7 @@ class Predictor():
 	def __init__(self, args):
 		print("\n\tLoading NLP model...")
 		self.infile = args.i
-		self.model = tf.keras.models.load_model("nlpModel")
+		self.model = tf.keras.models.load_model(args.m)
 		self.names = []
 		self.outfile = args.o
 		self.__getNames__()
@@ -41,6 +41,7 @@ class Predictor():
 def main():
 	start = datetime.now()
 	parser = ArgumentParser("Predicts whether input names are common or scientific.")
+	parser.add_argument("-m", default = "nlpModel", help = "Path to trained model directory (default is nlpModel).")
 	parser.add_argument("i", help = "Path to input file. Must be a text file with a sinlge column of species names.")
 	parser.add_argument("o", help = "Path to output file.")
 	Predictor(parser.parse_args())
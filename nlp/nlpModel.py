@@ -12,6 +12,9 @@ import tensorflow_hub as hub
 from tensorflow.keras.preprocessing.text import Tokenizer
 from tensorflow.keras.preprocessing.sequence import pad_sequences
 
+# Pretrained text embeddings by common name language
+HUBS = {"English": "en", "Spanish": "es", "German": "de", "Japanese": "ja", "Chinese": "zh", "Indonesian": "id", "Korean": "ko"}
+
 class Classifier():
 
 	def __init__(self, args):
@@ -21,6 +24,7 @@ class Classifier():
 		self.host = "localhost"
 		self.hub = "https://tfhub.dev/google/nnlm-en-dim50/2"
 		self.labels_test = []
+		self.language = args.l
 		self.labels_train = []
 		self.model = None
 		self.outfile = "nlpModel"
@@ -28,9 +32,21 @@ class Classifier():
 		self.train = []
 		self.training_size = 10000
 		self.username = args.u
+		self.__setLanguage__()
 		self.__connect__()
 		self.__getDataSets__()
 
+	def __setLanguage__(self):
+		# Sets embedding and output model for common name language
+		if self.language:
+			self.language = self.language.strip().title()
+			if self.language not in HUBS:
+				print(("\n\tNo embedding is available for {}. Exiting.").format(self.language))
+				quit()
+			self.hub = ("https://tfhub.dev/google/nnlm-{}-dim50/2").format(HUBS[self.language])
+			if self.language != "English":
+				self.outfile = ("nlpModel-{}").format(self.language)
+
 	def __connect__(self):
 		# Connects to taxonomy database
 		print()
@@ -50,12 +66,15 @@ class Classifier():
 			print("\n\tIncorrect username or password. Exiting.")
 			quit()
 
-	def __getList__(self, column, table, name):
-		# Extracts target column from table
+	def __getList__(self, column, table, name, language = None):
+		# Extracts target column from table (optionally for a single common name language)
 		ret = []
 		cursor = self.db.cursor()
-		sql = ("SELECT DISTINCT({}) FROM {};").format(column, table)
-		cursor.execute(sql)
+		sql = ("SELECT DISTINCT({}) FROM {}").format(column, table)
+		if language:
+			cursor.execute(sql + " WHERE Language = %s;", (language,))
+		else:
+			cursor.execute(sql + ";")
 		for i in cursor.fetchall():
 			ret.append([name, i[0].strip()])
 		return ret
@@ -65,7 +84,7 @@ class Classifier():
 		train = []
 		labels = []
 		print("\n\tReading SQL tables...")
-		names = self.__getList__("Name", "Common", 0)
+		names = self.__getList__("Name", "Common", 0, self.language)
 		names.extend(self.__getList__("Species", "Taxonomy", 1))
 		shuffle(names)
 		# Get training and testing sets
@@ -119,6 +138,7 @@ def main():
 	start = datetime.now()
 	parser = ArgumentParser("")
 	parser.add_argument("-u", help = "MySQL username.")
+	parser.add_argument("-l", help = "Common name language to train on (model is saved to nlpModel-<language> for languages other than English).")
 	c = Classifier(parser.parse_args())
 	c.trainModel()
 	c.save()
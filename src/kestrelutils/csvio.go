@@ -0,0 +1,219 @@
+// Defines delimited file readers and writers with RFC 4180 quoting
+
+package kestrelutils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var BOM = "\ufeff"
+
+func Delimiter(filename string) rune {
+	// Returns tab for tsv files and comma otherwise
+	name := strings.TrimSuffix(strings.ToLower(filename), ".gz")
+	switch filepath.Ext(name) {
+	case ".tsv", ".tab":
+		return '\t'
+	}
+	return ','
+}
+
+func sniffDelimiter(line string) rune {
+	// Returns tab if first line contains more tabs than commas
+	if strings.Count(line, "\t") > strings.Count(line, ",") {
+		return '\t'
+	}
+	return ','
+}
+
+type Reader struct {
+	Delim  rune
+	Header map[string]int
+	Names  []string
+	file   *os.File
+	gz     *gzip.Reader
+	reader *csv.Reader
+}
+
+func NewReader(infile string, header bool) (*Reader, error) {
+	// Opens infile (optionally gzipped) and reads header if present
+	var err error
+	var in io.Reader
+	r := new(Reader)
+	if r.file, err = os.Open(infile); err != nil {
+		return nil, err
+	}
+	in = r.file
+	if strings.HasSuffix(strings.ToLower(infile), ".gz") {
+		if r.gz, err = gzip.NewReader(r.file); err != nil {
+			r.file.Close()
+			return nil, err
+		}
+		in = r.gz
+	}
+	buf := bufio.NewReader(in)
+	// Determine delimiter from first line without consuming it
+	first, _ := buf.Peek(4096)
+	line := strings.SplitN(string(first), "\n", 2)[0]
+	r.Delim = sniffDelimiter(line)
+	r.reader = csv.NewReader(buf)
+	r.reader.Comma = r.Delim
+	r.reader.FieldsPerRecord = -1
+	r.reader.LazyQuotes = true
+	r.Header = make(map[string]int)
+	if header {
+		if r.Names, err = r.Read(); err != nil && err != io.EOF {
+			r.Close()
+			return nil, err
+		}
+		for idx, i := range r.Names {
+			r.Header[i] = idx
+		}
+	}
+	return r, nil
+}
+
+func (r *Reader) Read() ([]string, error) {
+	// Returns next row with surrounding whitespace and byte order marks removed
+	row, err := r.reader.Read()
+	for idx, i := range row {
+		row[idx] = strings.TrimSpace(strings.TrimPrefix(i, BOM))
+	}
+	return row, err
+}
+
+func IsParseError(err error) bool {
+	// Returns true if err is a malformed row which may be skipped (other read errors will not resolve by reading further)
+	_, ok := err.(*csv.ParseError)
+	return ok
+}
+
+func (r *Reader) Column(col string) (int, error) {
+	// Returns index of column given as a header name or integer (-1 denotes a single column file)
+	col = strings.TrimSpace(col)
+	if col == "" {
+		return -1, nil
+	} else if idx, err := strconv.Atoi(col); err == nil {
+		if idx >= 0 && len(r.Names) > 0 && idx >= len(r.Names) {
+			return idx, fmt.Errorf("column %d is out of range for %d columns", idx, len(r.Names))
+		}
+		return idx, nil
+	}
+	for idx, i := range r.Names {
+		if strings.EqualFold(i, col) {
+			return idx, nil
+		}
+	}
+	return -1, fmt.Errorf("column %s not found in header", col)
+}
+
+func (r *Reader) Close() {
+	// Closes underlying files
+	if r.gz != nil {
+		r.gz.Close()
+	}
+	r.file.Close()
+}
+
+type Output struct {
+	file *os.File
+	gz   *gzip.Writer
+}
+
+func NewOutput(outfile string, appendto bool) (*Output, error) {
+	// Creates (or appends to) outfile; output is gzipped if outfile ends in .gz
+	var err error
+	o := new(Output)
+	if appendto {
+		o.file, err = os.OpenFile(outfile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	} else {
+		o.file, err = os.Create(outfile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(strings.ToLower(outfile), ".gz") {
+		o.gz = gzip.NewWriter(o.file)
+	}
+	return o, nil
+}
+
+func (o *Output) Write(p []byte) (int, error) {
+	// Writes p to file or gzip stream
+	if o.gz != nil {
+		return o.gz.Write(p)
+	}
+	return o.file.Write(p)
+}
+
+func (o *Output) Close() error {
+	// Closes gzip stream and file
+	var err error
+	if o.gz != nil {
+		err = o.gz.Close()
+	}
+	if e := o.file.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+type Writer struct {
+	file   *Output
+	writer *csv.Writer
+}
+
+func NewWriter(outfile string, appendto bool) (*Writer, error) {
+	// Creates (or appends to) outfile using the delimiter for its extension
+	var err error
+	w := new(Writer)
+	if w.file, err = NewOutput(outfile, appendto); err != nil {
+		return nil, err
+	}
+	w.writer = csv.NewWriter(w.file)
+	w.writer.Comma = Delimiter(outfile)
+	return w, nil
+}
+
+func (w *Writer) Write(row []string) error {
+	// Writes quoted row
+	return w.writer.Write(row)
+}
+
+func (w *Writer) Close() error {
+	// Flushes buffered rows and closes file
+	w.writer.Flush()
+	err := w.writer.Error()
+	if e := w.file.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func WriteCSV(outfile string, header []string, rows [][]string) error {
+	// Writes header and rows to outfile
+	w, err := NewWriter(outfile, false)
+	if err != nil {
+		return err
+	}
+	if len(header) > 0 {
+		w.Write(header)
+	}
+	for _, i := range rows {
+		if err = w.Write(i); err != nil {
+			break
+		}
+	}
+	if e := w.Close(); err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+// Tests delimited file readers and writers
+
+package kestrelutils
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func tempDir(t *testing.T) string {
+	// Returns temporary directory
+	dir, err := ioutil.TempDir("", "kestrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRoundTrip(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	header := []string{"Query", "Species", "Source"}
+	rows := [][]string{
+		{"pronghorn", "Antilocapra americana", "Ord, 1815"},
+		{`"Sheila" the fingerfish`, "NA", ""},
+	}
+	for _, name := range []string{"test.csv", "test.tsv", "test.csv.gz"} {
+		outfile := path.Join(dir, name)
+		if err := WriteCSV(outfile, header, rows); err != nil {
+			t.Fatal(err)
+		}
+		r, err := NewReader(outfile, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Delim != Delimiter(outfile) {
+			t.Errorf("Actual delimiter %q does not equal expected: %q", r.Delim, Delimiter(outfile))
+		}
+		for _, e := range rows {
+			a, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for idx := range e {
+				if a[idx] != e[idx] {
+					t.Errorf("Actual value %s does not equal expected: %s", a[idx], e[idx])
+				}
+			}
+		}
+		if _, err := r.Read(); err != io.EOF {
+			t.Errorf("Expected end of file after %d rows.", len(rows))
+		}
+		r.Close()
+	}
+}
+
+func TestColumn(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	infile := path.Join(dir, "bom.csv")
+	ioutil.WriteFile(infile, []byte(BOM+"ID,Common Name\n1,\"Fox, gray\"\n"), 0644)
+	r, err := NewReader(infile, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	cols := []struct {
+		input    string
+		expected int
+		pass     bool
+	}{
+		{"ID", 0, true},
+		{"common name", 1, true},
+		{"1", 1, true},
+		{"-1", -1, true},
+		{"2", 2, false},
+		{"Species", -1, false},
+	}
+	for _, i := range cols {
+		a, err := r.Column(i.input)
+		if (err == nil) != i.pass {
+			t.Errorf("Unexpected error status for column %s: %v", i.input, err)
+		} else if i.pass && a != i.expected {
+			t.Errorf("Actual index for %s %d does not equal expected: %d", i.input, a, i.expected)
+		}
+	}
+	if row, _ := r.Read(); len(row) != 2 || row[1] != "Fox, gray" {
+		t.Errorf("Quoted field was not read correctly: %v", row)
+	}
+}
+
+func TestTruncatedGzip(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	outfile := path.Join(dir, "truncated.csv.gz")
+	var rows [][]string
+	for i := 0; i < 20000; i++ {
+		rows = append(rows, []string{strconv.Itoa(i), "Canis lupus", "Canis lupus", "Linnaeus, 1758"})
+	}
+	if err := WriteCSV(outfile, []string{"Query", "SearchTerm", "Species", "Source"}, rows); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(outfile)
+	if err := os.Truncate(outfile, info.Size()/2); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := newTaxa(outfile, false, GetLogger())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Truncated gzip file did not return an error.")
+		} else if IsParseError(err) {
+			t.Errorf("Actual error %v is a parse error.", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Error("Reading truncated gzip file did not finish.")
+	}
+}
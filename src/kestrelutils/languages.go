@@ -0,0 +1,72 @@
+// Defines common name languages and their spelling dictionaries
+
+package kestrelutils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	// DICTIONARIES stores aspell dictionaries by vernacular language
+	DICTIONARIES = map[string]string{"English": "en_US", "French": "fr", "German": "de", "Italian": "it", "Portuguese": "pt_BR", "Spanish": "es"}
+	// UNSPECIFIED is stored for common names without a language
+	UNSPECIFIED = "unspecified"
+)
+
+func formatLanguage(language string) string {
+	// Capitalizes each word of language as stored by ITIS
+	s := strings.Fields(strings.ToLower(language))
+	for idx, i := range s {
+		r := []rune(i)
+		r[0] = unicode.ToUpper(r[0])
+		s[idx] = string(r)
+	}
+	return strings.Join(s, " ")
+}
+
+func ParseLanguages(languages string) ([]string, error) {
+	// Returns comma-seperated languages for searches; English is returned if languages is empty and nil (every language) for all
+	var ret []string
+	languages = strings.TrimSpace(languages)
+	if languages == "" {
+		return []string{"English"}, nil
+	} else if strings.EqualFold(languages, "all") {
+		return nil, nil
+	}
+	for _, i := range strings.Split(languages, ",") {
+		if i = formatLanguage(i); i == "" {
+			return nil, fmt.Errorf("empty language in %s", languages)
+		}
+		ret = append(ret, i)
+	}
+	return ret, nil
+}
+
+func KeepLanguage(languages []string, language string) bool {
+	// Returns true if common names in language should be used in searches with given languages (every language is used if empty)
+	if len(languages) == 0 || language == "" || strings.EqualFold(language, UNSPECIFIED) {
+		return true
+	}
+	for _, i := range languages {
+		if strings.EqualFold(i, language) {
+			return true
+		}
+	}
+	return false
+}
+
+func Dictionaries(languages []string) []string {
+	// Returns aspell dictionaries for search languages in the order they were given
+	var ret []string
+	if len(languages) == 0 {
+		languages = []string{"English"}
+	}
+	for _, i := range languages {
+		if v, ex := DICTIONARIES[i]; ex {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
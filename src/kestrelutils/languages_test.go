@@ -0,0 +1,38 @@
+// Tests common name language selection
+
+package kestrelutils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLanguages(t *testing.T) {
+	input := []struct {
+		languages string
+		keep      map[string]bool
+		dicts     string
+	}{
+		{"", map[string]bool{"English": true, "Spanish": false, "unspecified": true}, "en_US"},
+		{"spanish, english", map[string]bool{"English": true, "Spanish": true, "German": false}, "es,en_US"},
+		{"all", map[string]bool{"English": true, "Spanish": true, "Japanese": true}, "en_US"},
+		{"Japanese", map[string]bool{"English": false, "Japanese": true, "": true}, ""},
+	}
+	for _, i := range input {
+		languages, err := ParseLanguages(i.languages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range i.keep {
+			if a := KeepLanguage(languages, k); a != v {
+				t.Errorf("Actual value %v for %s with %q does not equal expected: %v", a, k, i.languages, v)
+			}
+		}
+		if a := strings.Join(Dictionaries(languages), ","); a != i.dicts {
+			t.Errorf("Actual dictionaries %s for %q do not equal expected: %s", a, i.languages, i.dicts)
+		}
+	}
+	if _, err := ParseLanguages("Spanish,,English"); err == nil {
+		t.Error("Empty language did not return an error.")
+	}
+}
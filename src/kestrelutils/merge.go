@@ -4,54 +4,98 @@ package kestrelutils
 
 import (
 	"fmt"
-	"github.com/icwells/go-tools/iotools"
+	"io"
 	"log"
+	"os"
 	"strings"
 )
 
-func getHeader(row []string) map[string]int {
-	// Returns map of header indeces
-	ret := make(map[string]int)
-	for idx, i := range row {
-		ret[i] = idx
-	}
-	return ret
-}
-
 type taxamerger struct {
-	taxa map[string][]string
-	nas  []string
+	cols   []int
+	header []string
+	ids    bool
+	taxa   map[string][]string
+	nas    []string
 }
 
-func newTaxa(infile string, logger *log.Logger) taxamerger {
+func newTaxa(infile string, ids bool, logger *log.Logger) (taxamerger, error) {
 	// Reads in results as a map of string slices
 	var t taxamerger
+	t.ids = ids
 	t.taxa = make(map[string][]string)
-	t.nas = []string{"NA", "NA", "NA", "NA", "NA", "NA", "NA"}
-	var d string
-	var h map[string]int
-	first := true
 	logger.Println("Reading search result file...")
-	f := iotools.OpenFile(infile)
-	defer f.Close()
-	scanner := iotools.GetScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(string(scanner.Text()))
-		if first == false {
-			s := strings.Split(line, d)
-			// Query name: [taxonomy] (drops search term and urls)
-			if len(s) >= h["Species"] {
-				t.taxa[s[h["Query"]]] = s[h["Kingdom"] : h["Species"]+1]
-				// Additionally store scientific name as key
-				t.taxa[s[h["Species"]]] = s[h["Kingdom"] : h["Species"]+1]
+	reader, err := NewReader(infile, true)
+	if err != nil {
+		return t, err
+	}
+	defer reader.Close()
+	h := reader.Header
+	for _, i := range []string{"Query", "SearchTerm", "Species", "Source"} {
+		if _, ex := h[i]; !ex {
+			return t, fmt.Errorf("%s column not found in %s", i, infile)
+		}
+	}
+	// Taxonomy levels are stored between search term and source
+	start, end := h["SearchTerm"]+1, h["Source"]
+	t.setHeader(reader.Names[start:end])
+	t.setIdentifiers(h)
+	for {
+		s, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			if IsParseError(err) {
+				continue
+			}
+			return t, err
+		}
+		// Query name: [taxonomy] (drops search term and urls)
+		if len(s) >= end {
+			row := s[start:end:end]
+			for _, i := range t.cols {
+				if i < len(s) {
+					row = append(row, s[i])
+				} else {
+					row = append(row, "NA")
+				}
+			}
+			t.taxa[s[h["Query"]]] = row
+			// Additionally store scientific name as key
+			t.taxa[s[h["Species"]]] = row
+		}
+	}
+	return t, nil
+}
+
+func (t *taxamerger) setHeader(levels []string) {
+	// Stores level names and matching NAs
+	for _, i := range levels {
+		if i == "Species" {
+			i = "ScientificName"
+		}
+		t.header = append(t.header, i)
+		t.nas = append(t.nas, "NA")
+	}
+}
+
+func (t *taxamerger) setIdentifiers(h map[string]int) {
+	// Stores indeces of identifier and CITES columns present in result file
+	if t.ids {
+		for _, i := range IDENTIFIERS {
+			col := IdentifierColumn(i)
+			if idx, ex := h[col]; ex {
+				t.cols = append(t.cols, idx)
+				t.header = append(t.header, col)
+				t.nas = append(t.nas, "NA")
 			}
-		} else {
-			d, _ = iotools.GetDelim(line)
-			h = getHeader(strings.Split(line, d))
-			first = false
 		}
 	}
-	return t
+	if idx, ex := h["CITES"]; ex {
+		// Listings are only present if they were requested at search time
+		t.cols = append(t.cols, idx)
+		t.header = append(t.header, "CITES")
+		t.nas = append(t.nas, "NA")
+	}
 }
 
 func (t *taxamerger) getTaxa(n string) []string {
@@ -63,49 +107,72 @@ func (t *taxamerger) getTaxa(n string) []string {
 	return ret
 }
 
-func (t *taxamerger) mergeTaxonomy(infile string, c int, prepend bool, logger *log.Logger) (string, [][]string) {
+func (t *taxamerger) mergeTaxonomy(infile, col string, prepend bool, logger *log.Logger) ([]string, [][]string, error) {
 	// Returns header and merged results
-	first := true
 	var ret [][]string
-	var d, header string
+	var header []string
 	fmt.Println("Merging input file with taxonomies...")
-	f := iotools.OpenFile(infile)
-	defer f.Close()
-	scanner := iotools.GetScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(string(scanner.Text()))
-		if first == false {
-			s := strings.Split(line, d)
-			if len(s) >= c {
-				var row []string
-				taxa := t.getTaxa(s[c])
-				if prepend == false {
-					row = append(s, taxa...)
-				} else {
-					row = append(taxa, s...)
-				}
-				ret = append(ret, row)
+	reader, err := NewReader(infile, true)
+	if err != nil {
+		return header, ret, err
+	}
+	defer reader.Close()
+	c, err := reader.Column(col)
+	if err != nil {
+		return header, ret, err
+	}
+	if prepend == false {
+		header = append(reader.Names[:len(reader.Names):len(reader.Names)], t.header...)
+	} else {
+		header = append(t.header[:len(t.header):len(t.header)], reader.Names...)
+	}
+	for {
+		s, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			if IsParseError(err) {
+				continue
 			}
+			return header, ret, err
+		}
+		var name string
+		if c < 0 {
+			name = strings.Join(s, string(reader.Delim))
+		} else if len(s) > c {
+			name = s[c]
 		} else {
-			d, _ = iotools.GetDelim(line)
-			s := strings.Split(line, d)
-			if prepend == false {
-				header = strings.Join(s, ",") + ",Kingdom,Phylum,Class,Order,Family,Genus,ScientificName"
-			} else {
-				header = "Kingdom,Phylum,Class,Order,Family,Genus,ScientificName," + strings.Join(s, ",")
-			}
-			first = false
+			continue
+		}
+		var row []string
+		taxa := t.getTaxa(name)
+		if prepend == false {
+			row = append(s, taxa...)
+		} else {
+			row = append(taxa[:len(taxa):len(taxa)], s...)
 		}
+		ret = append(ret, row)
 	}
-	return header, ret
+	return header, ret, nil
 }
 
-func MergeResults(infile, resfile, outfile string, col int, prepend bool, logger *log.Logger) {
+func MergeResults(infile, resfile, outfile, col string, prepend, ids bool, logger *log.Logger) {
 	// Merges search results with source file
 	CheckFile(infile)
 	CheckFile(resfile)
-	taxa := newTaxa(resfile, logger)
-	header, results := taxa.mergeTaxonomy(infile, col, prepend, logger)
+	taxa, err := newTaxa(resfile, ids, logger)
+	if err != nil {
+		logger.Printf("[Error] Cannot read search results: %v\n", err)
+		os.Exit(1)
+	}
+	header, results, err := taxa.mergeTaxonomy(infile, col, prepend, logger)
+	if err != nil {
+		logger.Printf("[Error] Cannot merge input file: %v\n", err)
+		os.Exit(1)
+	}
 	logger.Println("Writing output...")
-	iotools.WriteToCSV(outfile, header, results)
+	if err := WriteCSV(outfile, header, results); err != nil {
+		logger.Printf("[Error] Cannot write output: %v\n", err)
+		os.Exit(1)
+	}
 }
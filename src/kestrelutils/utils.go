@@ -16,10 +16,17 @@ import (
 
 var (
 	APOSTROPHE = "%27"
-	SPACE = "%20"
-	SPACES = regexp.MustCompile(` +`)
+	SPACE      = "%20"
+	SPACES     = regexp.MustCompile(` +`)
+	// External databases which may provide taxon identifiers
+	IDENTIFIERS = []string{"ITIS", "NCBI", "EOL", "IUCN"}
 )
 
+func IdentifierColumn(source string) string {
+	// Returns output column name for identifier source
+	return source + "_ID"
+}
+
 func CorrectSpaces(s string) string {
 	// Removes redundant spaces between words
 	return SPACES.ReplaceAllString(s, " ")
@@ -35,13 +42,22 @@ func Getutils() string {
 	return path.Join(GetLocation(), "utils")
 }
 
-func GetAbsPath(f string) string {
-	// Prepends GOPATH to file name if needed
+func FindPath(f string) (string, error) {
+	// Prepends utils directory to file name if needed and returns an error if it does not exist
 	if !strings.Contains(f, string(os.PathSeparator)) {
 		f = path.Join(Getutils(), f)
 	}
 	if iotools.Exists(f) == false {
-		fmt.Printf("\n\t[Error] Cannot find %s file. Exiting.\n", f)
+		return f, fmt.Errorf("cannot find %s file", f)
+	}
+	return f, nil
+}
+
+func GetAbsPath(f string) string {
+	// Prepends GOPATH to file name if needed
+	f, err := FindPath(f)
+	if err != nil {
+		fmt.Printf("\n\t[Error] %v. Exiting.\n", err)
 		os.Exit(1)
 	}
 	return f
@@ -61,12 +77,19 @@ type Configuration struct {
 	Test     bool
 }
 
-func SetConfiguration(user string, test bool) Configuration {
-	// Gets setting from config.txt
+func ReadConfiguration(user string, test bool) (Configuration, error) {
+	// Gets setting from config.txt and returns an error if it cannot be read
 	var c Configuration
 	c.Test = test
 	c.User = user
-	f := iotools.OpenFile(GetAbsPath("config.txt"))
+	infile, err := FindPath("config.txt")
+	if err != nil {
+		return c, err
+	}
+	f, err := os.Open(infile)
+	if err != nil {
+		return c, err
+	}
 	defer f.Close()
 	scanner := iotools.GetScanner(f)
 	for scanner.Scan() {
@@ -82,32 +105,60 @@ func SetConfiguration(user string, test bool) Configuration {
 		case "test_database":
 			c.Testdb = s[1]
 		case "table_columns":
-			c.Tables = GetAbsPath(s[1])
+			if c.Tables, err = FindPath(s[1]); err != nil {
+				return c, err
+			}
 		}
 	}
+	return c, scanner.Err()
+}
+
+func SetConfiguration(user string, test bool) Configuration {
+	// Gets setting from config.txt
+	c, err := ReadConfiguration(user, test)
+	if err != nil {
+		fmt.Printf("\n\t[Error] %v. Exiting.\n", err)
+		os.Exit(1)
+	}
 	return c
 }
 
-func ConnectToDatabase(user, pw string, test bool) *dbIO.DBIO {
-	// Manages call to Connect and GetTableColumns
-	c := SetConfiguration(user, test)
+func OpenDatabase(c Configuration, pw string) (*dbIO.DBIO, error) {
+	// Connects to database given in configuration and reads table columns
 	d := c.Database
 	if c.Test == true {
 		d = c.Testdb
 	}
 	db, err := dbIO.Connect(c.Host, d, c.User, pw)
+	if err != nil {
+		return nil, err
+	}
+	db.GetTableColumns()
+	return db, nil
+}
+
+func ConnectToDatabase(user, pw string, test bool) *dbIO.DBIO {
+	// Manages call to Connect and GetTableColumns
+	db, err := OpenDatabase(SetConfiguration(user, test), pw)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1000)
 	}
-	db.GetTableColumns()
 	return db
 }
 
+func VerifyFile(infile string) error {
+	// Returns an error if input file does not exist
+	if iotools.Exists(infile) == false {
+		return fmt.Errorf("input file %s not found", infile)
+	}
+	return nil
+}
+
 func CheckFile(infile string) {
 	// Makes sure imut file exists
-	if iotools.Exists(infile) == false {
-		fmt.Printf("\n\t[Error] Input file %s not found. Exiting.\n\n", infile)
+	if err := VerifyFile(infile); err != nil {
+		fmt.Printf("\n\t[Error] %v. Exiting.\n\n", err)
 		os.Exit(1)
 	}
 }
@@ -6,7 +6,6 @@ import (
 	"bufio"
 	"fmt"
 	"github.com/icwells/dbIO"
-	"github.com/icwells/go-tools/iotools"
 	"github.com/icwells/kestrel/src/kestrelutils"
 	"github.com/icwells/kestrel/src/searchtaxa"
 	"github.com/icwells/kestrel/src/taxonomy"
@@ -27,18 +26,68 @@ var (
 
 	ver = kingpin.Command("version", "Prints version info and exits.")
 
-	upload = kingpin.Command("upload", "Formats and uploads taxonomy databases to MySQL database for searching. Databases must first be downloaded into the databases directory using './install.sh dowload'.")
+	upload      = kingpin.Command("upload", "Formats and uploads taxonomy databases to MySQL database for searching. Databases must first be downloaded into the databases directory using './install.sh dowload'.")
+	update      = upload.Flag("update", "Upserts ITIS (and CITES with --cites) taxa into the existing corpus instead of replacing it. Curated rows and other sources are kept, and changes are written to KestrelUpdates.csv (or --outfile).").Default("false").Bool()
+	itisdir     = upload.Flag("itis", "Directory of ITIS pipe-delimited download files (see './install.sh itis'); reads ITIS without the MySQL ITIS schema.").Default("").String()
+	priority    = upload.Flag("priority", "Comma-seperated order in which backbone lineages are preferred when sources share a name (lower priority sources only fill missing levels; conflicts are written to KestrelConflicts.csv).").Default(strings.Join(taxonomy.PRIORITY, ",")).String()
+	uploadcites = upload.Flag("cites", "Adds the bundled CITES checklist (utils/citesAnimalia.csv.gz) to the corpus and stores CITES listings.").Default("false").Bool()
+	uploadpass  = upload.Flag("password", "MySQL password for --update (for testing; will prompt for password by default).").String()
 
-	dump = kingpin.Command("dump", "Saves MySQL tables (if present) to current directory as csv files.")
+	dump       = kingpin.Command("dump", "Saves MySQL tables (if present) and a view of species joined with their common names as csv or json lines files.")
+	clades     = dump.Flag("clade", "Only write taxa in the given clade (i.e. Class=Aves); may be given more than once.").Strings()
+	dumpformat = dump.Flag("format", "Output format (csv or jsonl).").Default("csv").Enum("csv", "jsonl")
+	dumpgzip   = dump.Flag("gzip", "Compress output files.").Default("false").Bool()
+	dumppass   = dump.Flag("password", "MySQL password (for testing; will prompt for password by default).").String()
+	outdir     = dump.Flag("outdir", "Output directory.").Default(".").String()
+	tables     = dump.Flag("tables", "Comma-seperated list of tables to write (Species writes the joined view; all tables are written by default).").Default("").String()
 
-	search   = kingpin.Command("search", "Searches for taxonomy matches to input names.")
-	col      = search.Flag("column", "Column containing species names (integer starting from 0; use -1 for a single column file).").Default("-1").Short('c').Int()
-	nocorpus = search.Flag("nocorpus", "Perform web search without searching SQL corpus.").Default("false").Bool()
-	password = search.Flag("password", "MySQL password (for testing; will prompt for password by default).").String()
+	restore       = kingpin.Command("restore", "Loads tables saved by dump (csv or csv.gz) into a new database, or into the existing database with --update.")
+	restoredir    = restore.Flag("dir", "Directory containing dumped tables.").Default(".").Short('d').String()
+	restorepass   = restore.Flag("password", "MySQL password for --update (for testing; will prompt for password by default).").String()
+	restoreupdate = restore.Flag("update", "Updates taxonomies with the same source and identifier (or name) and adds new ones instead of replacing the database.").Default("false").Bool()
 
-	merge   = kingpin.Command("merge", "Merges search results with source file.")
-	prepend = merge.Flag("prepend", "Prepend taxonomies to existing rows (appends by default).").Default("false").Bool()
-	resfile = merge.Flag("result", "Path to Kestrel search result file.").Required().Short('r').String()
+	search      = kingpin.Command("search", "Searches for taxonomy matches to input names.")
+	format      = search.Flag("format", "Output format for search results (csv or jsonl; jsonl writes matches and misses to the output file).").Default("csv").Enum("csv", "jsonl")
+	col         = search.Flag("column", "Column containing species names (header name or integer starting from 0; use -1 for a single column file).").Default("-1").Short('c').String()
+	ids         = search.Flag("ids", "Comma-seperated list of external identifiers to write (ITIS, NCBI, EOL, IUCN; use 'all' for every source).").Default("").String()
+	levels      = search.Flag("levels", "Comma-seperated list of additional taxonomic levels to write (i.e. subfamily,tribe,subspecies; use 'all' for every level).").Default("").String()
+	blocklist   = search.Flag("blocklist", "Path to csv file of Name and Species pairs which may not be matched.").Default("").String()
+	breeds      = search.Flag("breeds", "Path to csv file of Species, Breed, and Aliases columns; breeds and breed crosses are searched as their domestic species and written to the Breed column.").Default("breeds.csv").String()
+	descriptors = search.Flag("descriptors", "Path to csv file of Category and Descriptor columns; matching words are removed from search terms and written to a column for each category.").Default("descriptors.csv").String()
+	overrides   = search.Flag("overrides", "Path to csv file of Name and Species (and optionally other taxonomic levels) which are assigned without searching.").Default("").String()
+	language    = search.Flag("language", "Comma-seperated list of common name languages used for matching, spelling correction, and classification (i.e. English,Spanish; use 'all' for every language).").Default("English").String()
+	cites       = search.Flag("cites", "Writes a CITES column indicating whether each match is CITES-listed (requires uploading with --cites).").Default("false").Bool()
+	nocorpus    = search.Flag("nocorpus", "Perform web search without searching SQL corpus.").Default("false").Bool()
+	password    = search.Flag("password", "MySQL password (for testing; will prompt for password by default).").String()
+
+	review            = kingpin.Command("review", "Interactively review unconfirmed and missed search results; decisions are written to the result file and KestrelReviewed.csv.")
+	reviewbreeds      = review.Flag("breeds", "Path to csv file of Species, Breed, and Aliases columns used to annotate reviewed queries.").Default("breeds.csv").String()
+	reviewdescriptors = review.Flag("descriptors", "Path to csv file of Category and Descriptor columns used to annotate reviewed queries.").Default("descriptors.csv").String()
+	reviewfile        = review.Flag("result", "Path to Kestrel search result file (csv).").Required().Short('r').String()
+	reviewlanguage    = review.Flag("language", "Comma-seperated list of common name languages used to suggest alternatives (i.e. English,Spanish; use 'all' for every language).").Default("English").String()
+	reviewpass        = review.Flag("password", "MySQL password (for testing; will prompt for password by default).").String()
+
+	serve            = kingpin.Command("serve", "Loads the taxonomy corpus once and serves json resolution endpoints (/resolve?name=, POST /batch, /health, and an OpenRefine reconciliation service at /reconcile).")
+	servebreeds      = serve.Flag("breeds", "Path to csv file of Species, Breed, and Aliases columns; breeds and breed crosses are resolved as their domestic species and returned as annotations.").Default("breeds.csv").String()
+	servecites       = serve.Flag("cites", "Returns whether each match is CITES-listed (requires uploading with --cites).").Default("false").Bool()
+	servedescriptors = serve.Flag("descriptors", "Path to csv file of Category and Descriptor columns; matching words are removed from names and returned as annotations.").Default("descriptors.csv").String()
+	servelanguage    = serve.Flag("language", "Comma-seperated list of common name languages used for matching, spelling correction, and classification (i.e. English,Spanish; use 'all' for every language).").Default("English").String()
+	port             = serve.Flag("port", "Port to listen on.").Default("8080").Int()
+	servepass        = serve.Flag("password", "MySQL password (for testing; will prompt for password by default).").String()
+	timeout          = serve.Flag("timeout", "Maximum time to spend resolving each request.").Default("30s").Duration()
+	web              = serve.Flag("web", "Search online databases for names which are not found in the corpus.").Default("false").Bool()
+
+	learn     = kingpin.Command("learn", "Adds confirmed matches from a reviewed result file to the taxonomy corpus as curated entries.")
+	learnpass = learn.Flag("password", "MySQL password (for testing; will prompt for password by default).").String()
+
+	validate     = kingpin.Command("validate", "Checks taxonomies in a result or merged file against the corpus hierarchy and writes each violation with the suggested fix (KestrelViolations.csv by default).")
+	validatepass = validate.Flag("password", "MySQL password (for testing; will prompt for password by default).").String()
+
+	merge    = kingpin.Command("merge", "Merges search results with source file.")
+	mergecol = merge.Flag("column", "Column containing species names in the source file (header name or integer starting from 0; use -1 for a single column file).").Default("-1").Short('c').String()
+	mergeids = merge.Flag("ids", "Include external identifier columns from the result file.").Default("false").Bool()
+	prepend  = merge.Flag("prepend", "Prepend taxonomies to existing rows (appends by default).").Default("false").Bool()
+	resfile  = merge.Flag("result", "Path to Kestrel search result file.").Required().Short('r').String()
 )
 
 func version() {
@@ -68,12 +117,37 @@ func newDatabase() *dbIO.DBIO {
 	return db
 }
 
-func dumpTables(db *dbIO.DBIO, logger *log.Logger) {
-	// Saves taxonomy and common name tables to current directory
-	for k, v := range db.Columns {
-		logger.Printf("Saving %s...\n", k)
-		iotools.WriteToCSV(fmt.Sprintf("%s.csv", k), v, db.GetTable(k))
+func searchSettings(breedfile, descriptorfile, languages, levels, ids string, cites bool) (*terms.Options, *taxonomy.Output) {
+	// Loads breeds, descriptors, and languages used to format names and the levels, identifiers, and CITES column written to results
+	options, output := terms.NewOptions(), taxonomy.NewOutput()
+	output.CITES = cites
+	for _, err := range []error{output.SetLevels(levels), output.SetIdentifiers(ids), options.SetLanguages(languages), options.LoadDescriptors(kestrelutils.GetAbsPath(descriptorfile)), options.LoadBreeds(kestrelutils.GetAbsPath(breedfile))} {
+		if err != nil {
+			fmt.Printf("\n\t[Error] %v. Exiting.\n\n", err)
+			os.Exit(1)
+		}
+	}
+	return options, output
+}
+
+func serveTaxonomies(db *dbIO.DBIO, logger *log.Logger) {
+	// Loads corpus and serves resolution endpoints
+	var err error
+	c := searchtaxa.Config{DB: db, Logger: logger, Processes: *proc, Web: *web}
+	c.Options, c.Output = searchSettings(*servebreeds, *servedescriptors, *servelanguage, "", "", *servecites)
+	if *web {
+		if c.Keys, err = searchtaxa.APIKeys(kestrelutils.GetAbsPath("API.txt")); err != nil {
+			logger.Printf("[Error] Cannot read API keys: %v\n", err)
+			os.Exit(1)
+		}
 	}
+	logger.Println("Loading taxonomy corpus...")
+	r, err := searchtaxa.NewResolver(c)
+	if err == nil {
+		err = searchtaxa.Serve(r, *port, *timeout, logger)
+	}
+	logger.Printf("[Error] %v\n", err)
+	os.Exit(1)
 }
 
 func main() {
@@ -84,27 +158,93 @@ func main() {
 	case ver.FullCommand():
 		version()
 	case upload.FullCommand():
-		db = newDatabase()
-		start = db.Starttime
-		logger.Println("Uploading taxonomies to MySQL database...")
-		taxonomy.UploadDatabases(db, *proc, logger)
+		if *update {
+			db = kestrelutils.ConnectToDatabase(*user, *uploadpass, false)
+			start = db.Starttime
+			logger.Println("Updating taxonomies in MySQL database...")
+			if err := taxonomy.UpdateDatabases(db, *proc, *itisdir, *uploadcites, strings.Split(strings.ToUpper(*priority), ","), *outfile, logger); err != nil {
+				logger.Printf("[Error] %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			db = newDatabase()
+			start = db.Starttime
+			logger.Println("Uploading taxonomies to MySQL database...")
+			taxonomy.UploadDatabases(db, *proc, *itisdir, *uploadcites, strings.Split(strings.ToUpper(*priority), ","), logger)
+		}
 	case dump.FullCommand():
-		db = kestrelutils.ConnectToDatabase(*user, *password, false)
+		db = kestrelutils.ConnectToDatabase(*user, *dumppass, false)
+		start = db.Starttime
+		opt := taxonomy.DumpOptions{Clades: *clades, Format: *dumpformat, Gzip: *dumpgzip, Outdir: *outdir}
+		if *tables != "" {
+			opt.Tables = strings.Split(*tables, ",")
+		}
+		logger.Printf("Saving taxonomy tables to %s...\n", *outdir)
+		if err := taxonomy.Dump(db, opt, logger); err != nil {
+			logger.Printf("[Error] %v\n", err)
+			os.Exit(1)
+		}
+	case restore.FullCommand():
+		if *restoreupdate {
+			db = kestrelutils.ConnectToDatabase(*user, *restorepass, false)
+		} else {
+			db = newDatabase()
+		}
 		start = db.Starttime
-		logger.Println("Saving taxonomy tables to current directory...")
-		dumpTables(db, logger)
+		logger.Printf("Restoring tables from %s...\n", *restoredir)
+		if err := taxonomy.Restore(db, *restoredir, *restoreupdate, logger); err != nil {
+			logger.Printf("[Error] %v\n", err)
+			os.Exit(1)
+		}
 	case search.FullCommand():
+		options, output := searchSettings(*breeds, *descriptors, *language, *levels, *ids, *cites)
+		ov, err := searchtaxa.LoadOverrides(*overrides, *blocklist)
+		if err != nil {
+			fmt.Printf("\n\t[Error] %v. Exiting.\n\n", err)
+			os.Exit(1)
+		}
 		db = kestrelutils.ConnectToDatabase(*user, *password, false)
 		logger.Println("Extracting search terms...")
 		start = db.Starttime
-		searchterms := terms.ExtractSearchTerms(*infile, *outfile, *col, logger)
+		searchterms := terms.ExtractSearchTerms(*infile, *outfile, *col, options, logger)
 		logger.Printf("Current run time: %v\n", time.Since(start))
 		logger.Println("Searching for taxonomy matches...")
-		searchtaxa.SearchTaxonomies(db, *outfile, searchterms, *proc, *nocorpus, logger)
+		c := searchtaxa.Config{DB: db, Logger: logger, NoCorpus: *nocorpus, Options: options, Output: output, Overrides: ov, Processes: *proc}
+		searchtaxa.SearchTaxonomies(c, *outfile, *format, searchterms)
+	case review.FullCommand():
+		db = kestrelutils.ConnectToDatabase(*user, *reviewpass, false)
+		start = db.Starttime
+		options, _ := searchSettings(*reviewbreeds, *reviewdescriptors, *reviewlanguage, "", "", false)
+		r, err := searchtaxa.NewResolver(searchtaxa.Config{DB: db, Logger: logger, Options: options})
+		if err == nil {
+			err = searchtaxa.Review(r, *reviewfile, os.Stdin, os.Stdout)
+		}
+		if err != nil {
+			logger.Printf("[Error] %v\n", err)
+			os.Exit(1)
+		}
+	case serve.FullCommand():
+		db = kestrelutils.ConnectToDatabase(*user, *servepass, false)
+		start = db.Starttime
+		serveTaxonomies(db, logger)
+	case learn.FullCommand():
+		db = kestrelutils.ConnectToDatabase(*user, *learnpass, false)
+		start = db.Starttime
+		if err := taxonomy.Learn(db, *infile, logger); err != nil {
+			logger.Printf("[Error] %v\n", err)
+			os.Exit(1)
+		}
+	case validate.FullCommand():
+		db = kestrelutils.ConnectToDatabase(*user, *validatepass, false)
+		start = db.Starttime
+		if err := taxonomy.ValidateResults(db, *infile, *outfile, logger); err != nil {
+			logger.Printf("[Error] %v\n", err)
+			os.Exit(1)
+		}
 	case merge.FullCommand():
 		start = time.Now()
 		logger.Println("Merging search results with source file...")
-		kestrelutils.MergeResults(*infile, *resfile, *outfile, *col, *prepend, logger)
+		kestrelutils.MergeResults(*infile, *resfile, *outfile, *mergecol, *prepend, *mergeids, logger)
 	}
 	logger.Printf("Finished. Run time: %v\n\n", time.Since(start))
 }
@@ -0,0 +1,89 @@
+// Defines bk-tree index for fuzzy matching corpus names
+
+package searchtaxa
+
+import (
+	"github.com/lithammer/fuzzysearch/fuzzy"
+	"sort"
+)
+
+type bknode struct {
+	children map[int]*bknode
+	name     string
+}
+
+func newBKNode(name string) *bknode {
+	// Returns initialized node
+	n := new(bknode)
+	n.children = make(map[int]*bknode)
+	n.name = name
+	return n
+}
+
+type bktree struct {
+	root *bknode
+	size int
+}
+
+func newBKTree(names []string) *bktree {
+	// Returns tree containing each name
+	b := new(bktree)
+	for _, i := range names {
+		b.add(i)
+	}
+	return b
+}
+
+func (b *bktree) add(name string) {
+	// Inserts name into tree
+	if b.root == nil {
+		b.root = newBKNode(name)
+		b.size++
+		return
+	}
+	n := b.root
+	for {
+		dist := fuzzy.LevenshteinDistance(name, n.name)
+		if dist == 0 {
+			// Skip duplicates
+			return
+		}
+		child, ex := n.children[dist]
+		if !ex {
+			n.children[dist] = newBKNode(name)
+			b.size++
+			return
+		}
+		n = child
+	}
+}
+
+func (b *bktree) search(term string, max int) fuzzy.Ranks {
+	// Returns names within max edits of term which also contain each character of term (matching fuzzy.RankFindFold)
+	var ret fuzzy.Ranks
+	if b.root == nil || max < 0 {
+		return ret
+	}
+	stack := []*bknode{b.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		dist := fuzzy.LevenshteinDistance(term, n.name)
+		if dist <= max && fuzzy.MatchFold(term, n.name) {
+			ret = append(ret, fuzzy.Rank{Source: term, Target: n.name, Distance: dist})
+		}
+		// Only children within max of dist can be within max of term (triangle inequality)
+		for d, child := range n.children {
+			if d >= dist-max && d <= dist+max {
+				stack = append(stack, child)
+			}
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Distance == ret[j].Distance {
+			return ret[i].Target < ret[j].Target
+		}
+		return ret[i].Distance < ret[j].Distance
+	})
+	return ret
+}
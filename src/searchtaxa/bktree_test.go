@@ -0,0 +1,82 @@
+// Tests bk-tree index against linear fuzzy search
+
+package searchtaxa
+
+import (
+	"github.com/icwells/go-tools/iotools"
+	"github.com/icwells/simpleset"
+	"github.com/lithammer/fuzzysearch/fuzzy"
+	"testing"
+)
+
+var CORPUS = "../../utils/corpus.csv.gz"
+
+func corpusNames() []string {
+	// Returns species and common names from test corpus
+	set := simpleset.NewStringSet()
+	rows, header := iotools.ReadFile(CORPUS, true)
+	for _, i := range rows {
+		for _, col := range []string{"SearchTerm", "Species"} {
+			if idx, ex := header[col]; ex && idx < len(i) && i[idx] != "" {
+				set.Add(i[idx])
+			}
+		}
+	}
+	return set.ToStringSlice()
+}
+
+func linearSearch(term string, names []string) string {
+	// Returns best match from exhaustive search
+	var ret string
+	max := int(float64(len(term)) * 0.1)
+	matches := fuzzy.RankFindFold(term, names)
+	for _, i := range matches {
+		if i.Distance <= max && (ret == "" || i.Distance < fuzzy.LevenshteinDistance(term, ret) || (i.Distance == fuzzy.LevenshteinDistance(term, ret) && i.Target < ret)) {
+			ret = i.Target
+		}
+	}
+	return ret
+}
+
+func TestBKTree(t *testing.T) {
+	names := []string{"Canis latrans", "Canis lupus", "Gray wolf", "Gray fox", "Urocyon cinereoargenteus", "Coyote", "Acridotheres tristis"}
+	b := newBKTree(append(names, "Coyote"))
+	if b.size != len(names) {
+		t.Errorf("Actual tree size %d does not equal expected: %d", b.size, len(names))
+	}
+	for _, i := range []string{"Canis latrans", "Canis latrns", "Urocyon cinereoargenteu", "Gray wolf", "Grey wolf", "Coyote", "Acridotheres tristiss", "Heloderma suspectum"} {
+		var a string
+		e := linearSearch(i, names)
+		if matches := b.search(i, int(float64(len(i))*0.1)); matches.Len() > 0 {
+			a = matches[0].Target
+		}
+		if a != e {
+			t.Errorf("Actual match for %s %s does not equal expected: %s", i, a, e)
+		}
+	}
+}
+
+func benchmarkTerms() []string {
+	// Returns misspelled search terms
+	return []string{"Canis latrns", "Urocyon cinereoargenteu", "Short eared owl", "Podocnemis vogl", "Acridotheres tristiss", "Antilocapra americna"}
+}
+
+func BenchmarkRankFindFold(b *testing.B) {
+	names := corpusNames()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, i := range benchmarkTerms() {
+			fuzzy.RankFindFold(i, names)
+		}
+	}
+}
+
+func BenchmarkBKTree(b *testing.B) {
+	tree := newBKTree(corpusNames())
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, i := range benchmarkTerms() {
+			tree.search(i, int(float64(len(i))*0.1))
+		}
+	}
+}
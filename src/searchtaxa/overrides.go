@@ -0,0 +1,180 @@
+// Defines manual overrides and blocked matches which are applied before searching
+
+package searchtaxa
+
+import (
+	"fmt"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"github.com/icwells/kestrel/src/taxonomy"
+	"github.com/icwells/kestrel/src/terms"
+	"io"
+	"strings"
+)
+
+type Overrides struct {
+	blocked map[string]map[string]bool
+	levels  map[string]map[string]string
+}
+
+func NewOverrides() *Overrides {
+	// Returns empty struct
+	o := new(Overrides)
+	o.blocked = make(map[string]map[string]bool)
+	o.levels = make(map[string]map[string]string)
+	return o
+}
+
+func overrideKey(name string) string {
+	// Returns normalized name for matching queries and terms
+	return strings.ToLower(kestrelutils.CorrectSpaces(strings.TrimSpace(kestrelutils.PercentDecode(name))))
+}
+
+func readNameFile(infile string, f func(string, map[string]string)) error {
+	// Passes name and remaining columns (keyed by lower case header) of each row to f
+	reader, err := kestrelutils.NewReader(infile, true)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for _, i := range []string{"Name", "Species"} {
+		if _, ex := reader.Header[i]; !ex {
+			return fmt.Errorf("%s column not found in %s", i, infile)
+		}
+	}
+	name := reader.Header["Name"]
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil && !kestrelutils.IsParseError(err) {
+			return err
+		} else if err == nil && len(row) > name && row[name] != "" {
+			values := make(map[string]string)
+			for idx, i := range reader.Names {
+				if idx != name && idx < len(row) && row[idx] != "" {
+					values[strings.ToLower(i)] = row[idx]
+				}
+			}
+			f(overrideKey(row[name]), values)
+		}
+	}
+	return nil
+}
+
+func LoadOverrides(overfile, blockfile string) (*Overrides, error) {
+	// Reads overrides file (Name, Species, and optionally other level columns) and blocklist (Name, Species); either may be empty
+	o := NewOverrides()
+	if overfile != "" {
+		err := readNameFile(overfile, func(name string, values map[string]string) {
+			if values["species"] != "" {
+				o.levels[name] = values
+			}
+		})
+		if err != nil {
+			return o, err
+		}
+	}
+	if blockfile != "" {
+		err := readNameFile(blockfile, func(name string, values map[string]string) {
+			if _, ex := o.blocked[name]; !ex {
+				o.blocked[name] = make(map[string]bool)
+			}
+			o.blocked[name][strings.ToLower(values["species"])] = true
+		})
+		if err != nil {
+			return o, err
+		}
+	}
+	return o, nil
+}
+
+func (o *Overrides) Len() int {
+	// Returns number of overrides and blocked names
+	if o == nil {
+		return 0
+	}
+	return len(o.levels) + len(o.blocked)
+}
+
+func (o *Overrides) names(k string, t *terms.Term) []string {
+	// Returns normalized key, current term, and queries
+	ret := []string{overrideKey(k), overrideKey(t.Term)}
+	for _, i := range t.Queries {
+		ret = append(ret, overrideKey(i))
+	}
+	return ret
+}
+
+func (o *Overrides) override(k string, t *terms.Term) (map[string]string, bool) {
+	// Returns override levels for term or any of its queries
+	if o != nil {
+		for _, i := range o.names(k, t) {
+			if v, ex := o.levels[i]; ex {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (o *Overrides) blockedSpecies(k string, t *terms.Term) map[string]bool {
+	// Returns set of lower case species which may not be matched to term
+	ret := make(map[string]bool)
+	if o != nil {
+		for _, i := range o.names(k, t) {
+			for sp := range o.blocked[i] {
+				ret[sp] = true
+			}
+		}
+	}
+	return ret
+}
+
+func (s *searcher) overrideTaxonomy(levels map[string]string) *taxonomy.Taxonomy {
+	// Returns taxonomy from corpus for given species with any given levels replacing corpus values
+	t := taxonomy.NewTaxonomy()
+	species := t.SpeciesCaps(levels["species"])
+	if k := s.corpusMatch(species); k != "" {
+		t.Copy(s.taxa[k])
+	} else {
+		t.Genus = strings.Fields(species)[0]
+	}
+	for k, v := range levels {
+		if l := t.IsLevel(k, false); l != "" {
+			t.SetLevel(l, v)
+		}
+	}
+	t.Species = species
+	t.Source = "override"
+	t.Found = true
+	s.hier.FillTaxonomy(t)
+	return t
+}
+
+func (s *searcher) applyOverride(k string) bool {
+	// Sets override taxonomy for term and returns true if one was found
+	if levels, ex := s.overrides.override(k, s.terms[k]); ex {
+		t := s.terms[k]
+		t.Taxonomy.Copy(s.overrideTaxonomy(levels))
+		t.Confirm()
+		t.Confidence = 1.0
+		t.Sources = []string{"override"}
+		return true
+	}
+	return false
+}
+
+func (s *searcher) applyOverrides(callback func(string, bool)) int {
+	// Sets override taxonomies, passes them to callback, and removes them from the search
+	var count int
+	if s.overrides.Len() > 0 {
+		for k := range s.terms {
+			if s.applyOverride(k) {
+				callback(k, true)
+				delete(s.terms, k)
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,53 @@
+// Tests overrides and blocked matches
+
+package searchtaxa
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kestrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	overfile := path.Join(dir, "overrides.csv")
+	blockfile := path.Join(dir, "blocklist.csv")
+	ioutil.WriteFile(overfile, []byte("Name,Species,Family\nGILA MONSTER,abronia graminea,\nTiger barb,Puntigrus tetrazona,Cyprinidae\n"), 0644)
+	ioutil.WriteFile(blockfile, []byte("Name,Species\ncricket,Acheta domesticus\n"), 0644)
+	ov, err := LoadOverrides(overfile, blockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := testResolver()
+	r.searcher.overrides = ov
+	res, err := r.Resolve(context.Background(), []string{"Gila monster", "tiger barb", "Cricket", "Acridotheres tristis"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []struct {
+		family  string
+		species string
+		status  string
+	}{
+		{"Anguidae", "Abronia graminea", "confirmed"},
+		{"Cyprinidae", "Puntigrus tetrazona", "confirmed"},
+		{"", "", "missed"},
+		{"Sturnidae", "Acridotheres tristis", "confirmed"},
+	}
+	for idx, i := range exp {
+		if res[idx].Status != i.status || res[idx].Ranks["species"] != i.species {
+			t.Errorf("Actual result %s: %s does not equal expected: %s: %s", res[idx].Status, res[idx].Ranks["species"], i.status, i.species)
+		} else if i.family != "" && res[idx].Ranks["family"] != i.family {
+			t.Errorf("Actual family %s does not equal expected: %s", res[idx].Ranks["family"], i.family)
+		}
+	}
+	if res[0].Sources[0] != "override" {
+		t.Errorf("Actual source %v does not equal expected: override", res[0].Sources)
+	}
+}
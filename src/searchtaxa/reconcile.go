@@ -0,0 +1,386 @@
+// Defines OpenRefine reconciliation service endpoints
+
+package searchtaxa
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/icwells/go-tools/strarray"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"github.com/icwells/kestrel/src/taxonomy"
+	"github.com/icwells/kestrel/src/terms"
+	"html"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// CALLBACK matches valid jsonp callback names
+	CALLBACK = regexp.MustCompile(`^[A-Za-z_$][0-9A-Za-z_$.]*$`)
+	// TAXONTYPE is the only reconciliation type
+	TAXONTYPE = reconType{"taxon", "Taxon"}
+)
+
+type reconType struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type reconProperty struct {
+	PID string      `json:"pid"`
+	V   interface{} `json:"v"`
+}
+
+type reconQuery struct {
+	Limit      int             `json:"limit"`
+	Properties []reconProperty `json:"properties"`
+	Query      string          `json:"query"`
+	Type       string          `json:"type"`
+}
+
+type reconCandidate struct {
+	Description string      `json:"description,omitempty"`
+	ID          string      `json:"id"`
+	Match       bool        `json:"match"`
+	Name        string      `json:"name"`
+	Score       float64     `json:"score"`
+	Type        []reconType `json:"type"`
+}
+
+type reconResult struct {
+	Result []reconCandidate `json:"result"`
+}
+
+type extendRequest struct {
+	IDs        []string    `json:"ids"`
+	Properties []reconType `json:"properties"`
+}
+
+type extendValue struct {
+	Str string `json:"str"`
+}
+
+type extendResponse struct {
+	Meta []reconType                         `json:"meta"`
+	Rows map[string]map[string][]extendValue `json:"rows"`
+}
+
+func reconProperties() []reconType {
+	// Returns taxonomic levels, identifiers, and source as extendable properties
+	var ret []reconType
+	for _, i := range taxonomy.LEVELS {
+		ret = append(ret, reconType{i, strarray.TitleCase(i)})
+	}
+	for _, i := range kestrelutils.IDENTIFIERS {
+		ret = append(ret, reconType{kestrelutils.IdentifierColumn(i), i + " ID"})
+	}
+	return append(ret, reconType{"source", "Source"})
+}
+
+func propertyValue(t *taxonomy.Taxonomy, pid string) string {
+	// Returns value of property for given taxonomy or an empty string if it is not present
+	var ret string
+	if l := t.IsLevel(pid, false); l != "" {
+		ret = t.Get(l)
+	} else if strings.EqualFold(pid, "source") {
+		ret = t.Source
+	} else {
+		for _, i := range kestrelutils.IDENTIFIERS {
+			if strings.EqualFold(pid, kestrelutils.IdentifierColumn(i)) {
+				ret = t.Identifiers[i]
+			}
+		}
+	}
+	if strings.ToUpper(ret) == "NA" {
+		ret = ""
+	}
+	return ret
+}
+
+func queryValue(v interface{}) string {
+	// Returns string value of query property given as string, list, or entity
+	switch val := v.(type) {
+	case string:
+		return val
+	case []interface{}:
+		if len(val) > 0 {
+			return queryValue(val[0])
+		}
+	case map[string]interface{}:
+		if name, ex := val["name"]; ex {
+			return queryValue(name)
+		} else if id, ex := val["id"]; ex {
+			return queryValue(id)
+		}
+	}
+	return ""
+}
+
+func lineage(t *taxonomy.Taxonomy) string {
+	// Returns major levels from kingdom to species
+	var ret []string
+	for _, i := range t.Values(taxonomy.MAJOR) {
+		if strings.ToUpper(i) != "NA" && i != "" {
+			ret = append(ret, i)
+		}
+	}
+	return strings.Join(ret, " > ")
+}
+
+func (s *server) baseURL(r *http.Request) string {
+	// Returns url of reconciliation service as seen by the client
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/reconcile", scheme, r.Host)
+}
+
+func (s *server) writeRecon(w http.ResponseWriter, r *http.Request, v interface{}) {
+	// Writes json response, wrapped in callback for jsonp requests
+	callback := r.FormValue("callback")
+	if callback == "" {
+		s.writeJSON(w, http.StatusOK, v)
+		return
+	} else if !CALLBACK.MatchString(callback) {
+		s.writeError(w, http.StatusBadRequest, "Invalid callback")
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/javascript")
+	fmt.Fprintf(w, "%s(%s)", callback, b)
+}
+
+func (s *server) manifest(w http.ResponseWriter, r *http.Request) {
+	// Writes service manifest
+	base := s.baseURL(r)
+	preview := base + "/preview?id={{id}}"
+	suggest := func(path string) map[string]string {
+		return map[string]string{"service_url": base, "service_path": path}
+	}
+	s.writeRecon(w, r, map[string]interface{}{
+		"versions":        []string{"0.1", "0.2"},
+		"name":            "Kestrel taxonomy reconciliation",
+		"identifierSpace": "https://github.com/icwells/Kestrel/taxon",
+		"schemaSpace":     "https://github.com/icwells/Kestrel/schema",
+		"defaultTypes":    []reconType{TAXONTYPE},
+		"view":            map[string]string{"url": preview},
+		"preview":         map[string]interface{}{"url": preview, "width": 400, "height": 200},
+		"suggest": map[string]interface{}{
+			"entity":   suggest("/suggest/entity"),
+			"property": suggest("/suggest/property"),
+			"type":     suggest("/suggest/type"),
+		},
+		"extend": map[string]interface{}{
+			"propose_properties": suggest("/properties"),
+			"property_settings":  []string{},
+		},
+	})
+}
+
+func (s *server) reconCandidates(name string, q reconQuery) []reconCandidate {
+	// Returns scored candidates for a single query
+	ret := []reconCandidate{}
+	sr := &s.resolver.searcher
+	for _, c := range sr.candidates(name, int(float64(len(name))*0.2), q.Limit) {
+		t := sr.taxa[c.key]
+		rc := reconCandidate{lineage(t), c.key, c.exact, t.Name(), 100.0 * c.score, []reconType{TAXONTYPE}}
+		for _, p := range q.Properties {
+			// Penalize candidates which conflict with given properties
+			if v := queryValue(p.V); v != "" && !strings.EqualFold(v, propertyValue(t, p.PID)) {
+				rc.Score *= 0.5
+				rc.Match = false
+			}
+		}
+		ret = append(ret, rc)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Score > ret[j].Score
+	})
+	// Only automatically match unambiguous candidates
+	if len(ret) > 1 && ret[1].Score == ret[0].Score {
+		ret[0].Match = false
+	}
+	return ret
+}
+
+func (s *server) queries(w http.ResponseWriter, r *http.Request, queries string) {
+	// Reconciles batch of queries
+	var q map[string]reconQuery
+	if err := json.Unmarshal([]byte(queries), &q); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot parse queries: %v", err))
+		return
+	}
+	var names []string
+	for _, v := range q {
+		names = append(names, v.Query)
+	}
+	formatted := make(map[string]*terms.Term)
+	searchterms, _ := terms.FormatTerms(names, false, s.resolver.searcher.options, s.resolver.quiet)
+	for _, v := range searchterms {
+		for _, i := range v.Queries {
+			formatted[i] = v
+		}
+	}
+	ret := make(map[string]reconResult)
+	for k, v := range q {
+		if v.Limit <= 0 {
+			v.Limit = 5
+		}
+		res := reconResult{[]reconCandidate{}}
+		// Try formatted and spell-checked terms before the raw query
+		for _, i := range []string{formattedTerm(formatted[v.Query]), correctedTerm(formatted[v.Query]), strings.TrimSpace(v.Query)} {
+			if len(res.Result) == 0 && i != "" {
+				res.Result = s.reconCandidates(i, v)
+			}
+		}
+		ret[k] = res
+	}
+	s.writeRecon(w, r, ret)
+}
+
+func formattedTerm(t *terms.Term) string {
+	// Returns formatted search term if t is not nil
+	if t != nil {
+		return t.Term
+	}
+	return ""
+}
+
+func correctedTerm(t *terms.Term) string {
+	// Returns spell-checked search term if t is not nil
+	if t != nil {
+		return t.Corrected
+	}
+	return ""
+}
+
+func (s *server) extend(w http.ResponseWriter, r *http.Request, extend string) {
+	// Returns requested properties for reconciled ids
+	var e extendRequest
+	if err := json.Unmarshal([]byte(extend), &e); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot parse extend request: %v", err))
+		return
+	}
+	names := make(map[string]string)
+	for _, i := range reconProperties() {
+		names[i.ID] = i.Name
+	}
+	ret := extendResponse{[]reconType{}, make(map[string]map[string][]extendValue)}
+	for _, p := range e.Properties {
+		ret.Meta = append(ret.Meta, reconType{p.ID, names[p.ID]})
+	}
+	for _, id := range e.IDs {
+		row := make(map[string][]extendValue)
+		for _, p := range e.Properties {
+			row[p.ID] = []extendValue{}
+			if t, ex := s.resolver.searcher.taxa[id]; ex {
+				if v := propertyValue(t, p.ID); v != "" {
+					row[p.ID] = append(row[p.ID], extendValue{v})
+				}
+			}
+		}
+		ret.Rows[id] = row
+	}
+	s.writeRecon(w, r, ret)
+}
+
+func (s *server) reconcile(w http.ResponseWriter, r *http.Request) {
+	// Dispatches query, extend, and manifest requests
+	if q := r.FormValue("queries"); q != "" {
+		s.queries(w, r, q)
+	} else if e := r.FormValue("extend"); e != "" {
+		s.extend(w, r, e)
+	} else {
+		s.manifest(w, r)
+	}
+}
+
+func (s *server) preview(w http.ResponseWriter, r *http.Request) {
+	// Writes html summary of taxonomy
+	t, ex := s.resolver.searcher.taxa[r.FormValue("id")]
+	if !ex {
+		http.NotFound(w, r)
+		return
+	}
+	var b strings.Builder
+	b.WriteString("<html><body><table>")
+	for _, i := range taxonomy.LEVELS {
+		if v := propertyValue(t, i); v != "" {
+			fmt.Fprintf(&b, "<tr><th>%s</th><td>%s</td></tr>", strarray.TitleCase(i), html.EscapeString(v))
+		}
+	}
+	fmt.Fprintf(&b, "<tr><th>Source</th><td>%s</td></tr></table></body></html>", html.EscapeString(t.Source))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+func suggestLimit(r *http.Request) int {
+	// Returns limit parameter or default
+	if l, err := strconv.Atoi(r.FormValue("limit")); err == nil && l > 0 {
+		return l
+	}
+	return 10
+}
+
+func (s *server) suggestEntity(w http.ResponseWriter, r *http.Request) {
+	// Returns taxa with names beginning with prefix, or fuzzy matches if none are found
+	ret := []reconCandidate{}
+	prefix := strings.ToLower(strings.TrimSpace(r.FormValue("prefix")))
+	limit := suggestLimit(r)
+	sr := &s.resolver.searcher
+	if prefix != "" {
+		var keys []string
+		for k := range sr.taxa {
+			if strings.HasPrefix(strings.ToLower(k), prefix) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if len(ret) >= limit {
+				break
+			}
+			ret = append(ret, reconCandidate{Description: lineage(sr.taxa[k]), ID: k, Name: k})
+		}
+		if len(ret) == 0 {
+			for _, c := range sr.candidates(strarray.TitleCase(prefix), int(float64(len(prefix))*0.2), limit) {
+				ret = append(ret, reconCandidate{Description: lineage(sr.taxa[c.key]), ID: c.key, Name: c.key})
+			}
+		}
+	}
+	s.writeRecon(w, r, map[string][]reconCandidate{"result": ret})
+}
+
+func (s *server) suggestProperty(w http.ResponseWriter, r *http.Request) {
+	// Returns properties with names beginning with prefix
+	ret := []reconType{}
+	prefix := strings.ToLower(r.FormValue("prefix"))
+	for _, i := range reconProperties() {
+		if strings.HasPrefix(strings.ToLower(i.Name), prefix) || strings.HasPrefix(strings.ToLower(i.ID), prefix) {
+			ret = append(ret, i)
+		}
+	}
+	s.writeRecon(w, r, map[string][]reconType{"result": ret})
+}
+
+func (s *server) suggestType(w http.ResponseWriter, r *http.Request) {
+	// Returns only service type
+	s.writeRecon(w, r, map[string][]reconType{"result": []reconType{TAXONTYPE}})
+}
+
+func (s *server) proposeProperties(w http.ResponseWriter, r *http.Request) {
+	// Returns extendable properties
+	props := reconProperties()
+	if l := suggestLimit(r); r.FormValue("limit") != "" && l < len(props) {
+		props = props[:l]
+	}
+	s.writeRecon(w, r, map[string]interface{}{"type": TAXONTYPE.ID, "properties": props})
+}
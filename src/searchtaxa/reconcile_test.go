@@ -0,0 +1,83 @@
+// Tests reconciliation service endpoints
+
+package searchtaxa
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func reconRequest(t *testing.T, path string, form url.Values, v interface{}) {
+	// Performs request against test server and unmarshals response into v
+	s := testServer()
+	w := httptest.NewRecorder()
+	s.handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?"+form.Encode(), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Actual status %d does not equal expected: %d", w.Code, http.StatusOK)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	var m map[string]interface{}
+	reconRequest(t, "/reconcile", url.Values{}, &m)
+	for _, i := range []string{"name", "identifierSpace", "schemaSpace", "defaultTypes", "preview", "suggest", "extend"} {
+		if _, ex := m[i]; !ex {
+			t.Errorf("Manifest does not contain %s.", i)
+		}
+	}
+}
+
+func TestQueries(t *testing.T) {
+	var res map[string]reconResult
+	q := `{"q0": {"query": "Gila monster"}, "q1": {"query": "Heloderma suspectm"}, "q2": {"query": "Acheta domesticus", "properties": [{"pid": "class", "v": "Aves"}]}}`
+	reconRequest(t, "/reconcile", url.Values{"queries": []string{q}}, &res)
+	exp := map[string]bool{"q0": true, "q1": false, "q2": false}
+	for k, v := range exp {
+		if len(res[k].Result) == 0 {
+			t.Errorf("No candidates found for %s.", k)
+		} else if res[k].Result[0].Match != v {
+			t.Errorf("Actual match %v for %s does not equal expected: %v", res[k].Result[0].Match, k, v)
+		}
+	}
+	if len(res["q1"].Result) > 0 && res["q1"].Result[0].ID != "Heloderma suspectum" {
+		t.Errorf("Actual candidate %s does not equal expected: Heloderma suspectum", res["q1"].Result[0].ID)
+	}
+	if len(res["q2"].Result) > 0 && res["q2"].Result[0].Score != 50.0 {
+		t.Errorf("Actual score %f does not equal expected: 50", res["q2"].Result[0].Score)
+	}
+}
+
+func TestExtend(t *testing.T) {
+	var res extendResponse
+	e := `{"ids": ["Acheta domesticus"], "properties": [{"id": "order"}, {"id": "genus"}]}`
+	reconRequest(t, "/reconcile", url.Values{"extend": []string{e}}, &res)
+	row := res.Rows["Acheta domesticus"]
+	for k, v := range map[string]string{"order": "Orthoptera", "genus": "Acheta"} {
+		if len(row[k]) != 1 || row[k][0].Str != v {
+			t.Errorf("Actual %s %v does not equal expected: %s", k, row[k], v)
+		}
+	}
+}
+
+func TestCallback(t *testing.T) {
+	s := testServer()
+	for k, v := range map[string]int{"jQuery_123.cb": http.StatusOK, "alert(1)//": http.StatusBadRequest, "<script>": http.StatusBadRequest, "1cb": http.StatusBadRequest} {
+		w := httptest.NewRecorder()
+		form := url.Values{"callback": []string{k}}
+		s.handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reconcile?"+form.Encode(), nil))
+		if w.Code != v {
+			t.Errorf("Actual status %d for callback %s does not equal expected: %d", w.Code, k, v)
+		} else if v == http.StatusOK && !strings.HasPrefix(w.Body.String(), k+"(") {
+			t.Errorf("Response is not wrapped in callback %s.", k)
+		} else if v != http.StatusOK && strings.Contains(w.Body.String(), k+"(") {
+			t.Errorf("Invalid callback %s was written to response.", k)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Defines importable resolver for matching names to taxonomies
+
+package searchtaxa
+
+import (
+	"context"
+	"errors"
+	"github.com/icwells/dbIO"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"github.com/icwells/kestrel/src/taxonomy"
+	"github.com/icwells/kestrel/src/terms"
+	"io/ioutil"
+	"log"
+	"time"
+)
+
+type Config struct {
+	Classify  bool              // Call scientific name classifier (requires python and the nlp directory)
+	DB        *dbIO.DBIO        // Existing connection; used in place of connection settings if given
+	Database  string            // Connection settings
+	Host      string            // Connection settings
+	Keys      map[string]string // API keys by source (i.e. IUCN, NCBI, EOL)
+	Logger    *log.Logger       // Progress logger; discarded if nil
+	NoCorpus  bool              // Skip corpus search
+	Options   *terms.Options    // Common name languages used to format and match names; English if nil
+	Output    *taxonomy.Output  // Levels, identifiers, and CITES column written to results; seven major levels if nil
+	Overrides *Overrides        // Manual overrides and blocked matches
+	Password  string            // Connection settings
+	Processes int               // Maximum number of concurrent searches
+	Selenium  bool              // Start selenium service for searches which APIs cannot resolve
+	User      string            // Connection settings
+	Web       bool              // Search online databases for names which are not found in the corpus
+}
+
+func (c *Config) connect() (*dbIO.DBIO, error) {
+	// Returns existing connection or connects with given settings
+	if c.DB != nil {
+		return c.DB, nil
+	}
+	if c.Database == "" {
+		return nil, errors.New("no database connection or database name given")
+	}
+	return kestrelutils.OpenDatabase(kestrelutils.Configuration{Host: c.Host, Database: c.Database, User: c.User}, c.Password)
+}
+
+type Result struct {
+	terms.Record
+	Taxonomy *taxonomy.Taxonomy `json:"-"`
+}
+
+type result struct {
+	found bool
+	key   string
+}
+
+type Resolver struct {
+	classify bool
+	logger   *log.Logger
+	proc     chan struct{}
+	quiet    *log.Logger
+	searcher searcher
+}
+
+func NewResolver(c Config) (*Resolver, error) {
+	// Connects to database and loads taxonomy corpus
+	db, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	r := new(Resolver)
+	r.classify = c.Classify
+	r.quiet = log.New(ioutil.Discard, "", 0)
+	r.logger = c.Logger
+	if r.logger == nil {
+		r.logger = r.quiet
+	}
+	if c.Processes < 1 {
+		c.Processes = 1
+	}
+	// Limits number of concurrent searches across all calls to Resolve
+	r.proc = make(chan struct{}, c.Processes)
+	if c.Options == nil {
+		c.Options = terms.NewOptions()
+	}
+	r.searcher = newSearcher(db, r.logger, nil, c.Options, c.NoCorpus)
+	if c.Output != nil {
+		// Copy output settings so CITES listings of this database are not shared
+		o := *c.Output
+		r.searcher.output = &o
+	}
+	if r.searcher.output.CITES {
+		r.searcher.output.SetCITES(db.GetTable("Cites"))
+	}
+	r.searcher.overrides = c.Overrides
+	r.searcher.web = c.Web
+	for k, v := range c.Keys {
+		r.searcher.keys[k] = v
+	}
+	if c.Selenium {
+		r.searcher.service = newService()
+	}
+	return r, nil
+}
+
+func (r *Resolver) Close() {
+	// Stops selenium service if it was started
+	if r.searcher.service.running() {
+		r.searcher.service.stop()
+	}
+	if r.searcher.service != nil {
+		r.searcher.service.KillChromeDrivers()
+	}
+}
+
+func (r *Resolver) dispatch(ctx context.Context, s *searcher, k string, ch chan<- result) {
+	// Performs search for k and releases its process slot
+	defer func() { <-r.proc }()
+	if found := s.findTerm(ctx, k); found || ctx.Err() == nil {
+		// Searches which were cut short by ctx are not reported as missed
+		ch <- result{found, k}
+	}
+}
+
+func (r *Resolver) search(ctx context.Context, s *searcher, callback func(string, bool)) error {
+	// Concurrently searches terms in s and passes each result to callback as it is received
+	var count int
+	ch := make(chan result, len(s.terms))
+	for k := range s.terms {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case r.proc <- struct{}{}:
+			// Only start searches once a process slot is available
+			go r.dispatch(ctx, s, k, ch)
+			if count++; s.web && count%10 == 0 {
+				// Pause after 10 to avoid swamping apis
+				time.Sleep(time.Second)
+			}
+		}
+	}
+	for i := 0; i < count; i++ {
+		select {
+		case res := <-ch:
+			callback(res.key, res.found)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return ctx.Err()
+}
+
+func results(names []string, searchterms map[string]*terms.Term, rejected []*terms.Term, found map[string]bool, o *taxonomy.Output) []Result {
+	// Returns one result per name in input order; unfinished searches are marked as timed out
+	var ret []Result
+	keys := make(map[string]string)
+	for k, v := range searchterms {
+		for _, q := range v.Queries {
+			keys[q] = k
+		}
+	}
+	reasons := make(map[string]*terms.Term)
+	for _, t := range rejected {
+		reasons[t.Queries[0]] = t
+	}
+	for _, i := range names {
+		var res Result
+		if k, ex := keys[i]; ex {
+			if f, ex := found[k]; ex {
+				res.Record = searchterms[k].Record(i, f, o)
+				if f {
+					res.Taxonomy = searchterms[k].Taxonomy
+				}
+			} else {
+				res.Record = terms.Record{Query: i, Term: kestrelutils.PercentDecode(k), Status: "timeout"}
+			}
+		} else if t, ex := reasons[i]; ex {
+			res.Record = t.Record(i, false, o)
+			res.Status = t.Status
+		} else {
+			res.Record = terms.Record{Query: i, Status: "invalid"}
+		}
+		ret = append(ret, res)
+	}
+	return ret
+}
+
+func (r *Resolver) Resolve(ctx context.Context, names []string) ([]Result, error) {
+	// Returns taxonomy results for names in input order; returns partial results and the context error if ctx expires
+	searchterms, rejected := terms.FormatTerms(names, r.classify, r.searcher.options, r.quiet)
+	pending := make(map[string]*terms.Term)
+	for k, v := range searchterms {
+		pending[k] = v
+	}
+	s := r.searcher.fork(pending)
+	found := make(map[string]bool)
+	callback := func(k string, f bool) {
+		found[k] = f
+	}
+	s.applyOverrides(callback)
+	err := r.search(ctx, s, callback)
+	return results(names, searchterms, rejected, found, s.output), err
+}
@@ -0,0 +1,149 @@
+// Tests resolver functions
+
+package searchtaxa
+
+import (
+	"context"
+	"github.com/icwells/kestrel/src/taxonomy"
+	"github.com/icwells/kestrel/src/terms"
+	"io/ioutil"
+	"log"
+	"testing"
+)
+
+func testResolver() *Resolver {
+	// Returns resolver with in-memory corpus
+	r := new(Resolver)
+	r.quiet = log.New(ioutil.Discard, "", 0)
+	r.logger = r.quiet
+	r.proc = make(chan struct{}, 2)
+	r.searcher.corpus = true
+	r.searcher.common = map[string]string{"Gila monster": "Heloderma suspectum", "Cricket": "Acheta domesticus"}
+	r.searcher.taxa = make(map[string]*taxonomy.Taxonomy)
+	names := []string{"Gila monster", "Cricket"}
+	taxa := taxaSlice()
+	for _, i := range taxa {
+		i.CountNAs()
+		r.searcher.taxa[i.Species] = i
+		names = append(names, i.Species)
+	}
+	r.searcher.index = newBKTree(names)
+	r.searcher.hier = taxonomy.NewHierarchy(taxa)
+	r.searcher.options = terms.NewOptions()
+	r.searcher.output = taxonomy.NewOutput()
+	return r
+}
+
+func TestResolver(t *testing.T) {
+	r := testResolver()
+	res, err := r.Resolve(context.Background(), []string{"Gila monster", "abronia graminea"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx, i := range []string{"Heloderma suspectum", "Abronia graminea"} {
+		if res[idx].Taxonomy == nil || res[idx].Taxonomy.Species != i {
+			t.Errorf("Actual taxonomy for %s does not equal expected: %s", res[idx].Query, i)
+		}
+	}
+}
+
+func TestResolverOutput(t *testing.T) {
+	// Resolvers with different output settings do not affect each other
+	a, b := testResolver(), testResolver()
+	b.searcher.output = taxonomy.NewOutput()
+	if err := b.searcher.output.SetLevels("subfamily"); err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []struct {
+		r   *Resolver
+		exp int
+	}{{a, 7}, {b, 8}} {
+		res, err := i.r.Resolve(context.Background(), []string{"Gila monster"})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(res) != 1 || len(res[0].Ranks) != i.exp {
+			t.Errorf("Actual number of ranks %d does not equal expected: %d", len(res[0].Ranks), i.exp)
+		}
+	}
+}
+
+func TestResolverCancel(t *testing.T) {
+	r := testResolver()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := r.Resolve(ctx, []string{"Gila monster"})
+	if err != context.Canceled {
+		t.Errorf("Actual error %v does not equal expected: %v", err, context.Canceled)
+	}
+	if len(res) != 1 || res[0].Status != "timeout" {
+		t.Errorf("Actual result %v does not equal expected: timeout", res)
+	}
+}
+
+func TestFindTermCancel(t *testing.T) {
+	r := testResolver()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	term := terms.NewTerm("gila monster")
+	term.Term = "Gila monster"
+	s := r.searcher.fork(map[string]*terms.Term{term.Term: term})
+	if s.findTerm(ctx, term.Term) {
+		t.Error("Search continued after context was cancelled.")
+	}
+}
+
+func TestResolverHybrid(t *testing.T) {
+	r := testResolver()
+	res, err := r.Resolve(context.Background(), []string{"Gila monster x Abronia graminea"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Taxonomy == nil {
+		t.Fatalf("Actual result %v does not equal expected: Squamata hybrid", res)
+	}
+	if a := res[0].Taxonomy; a.Order != "Squamata" || a.Family != "NA" || a.Species != "NA" {
+		t.Errorf("Actual lowest common taxonomy %s does not equal expected: Squamata", a.String())
+	}
+	exp := []string{"Heloderma suspectum", "Abronia graminea"}
+	if !res[0].Hybrid || len(res[0].Parents) != len(exp) {
+		t.Fatalf("Actual parents %v do not equal expected: %v", res[0].Parents, exp)
+	}
+	for idx, i := range exp {
+		if res[0].Parents[idx] != i {
+			t.Errorf("Actual parent %s does not equal expected: %s", res[0].Parents[idx], i)
+		}
+	}
+}
+
+func TestResolverHybridKingdoms(t *testing.T) {
+	r := testResolver()
+	plant := testtaxa([]string{"Plantae", "Tracheophyta", "Magnoliopsida", "Fagales", "Fagaceae", "Quercus", "Quercus alba"})
+	plant.CountNAs()
+	r.searcher.taxa[plant.Species] = plant
+	names := []string{"Gila monster", "Cricket"}
+	for k := range r.searcher.taxa {
+		names = append(names, k)
+	}
+	r.searcher.index = newBKTree(names)
+	res, err := r.Resolve(context.Background(), []string{"Gila monster x Quercus alba"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Taxonomy != nil {
+		t.Errorf("Hybrid of parents without a common kingdom was written as a match.")
+	}
+}
+
+func TestResolverQualifiers(t *testing.T) {
+	r := testResolver()
+	res, err := r.Resolve(context.Background(), []string{"Heloderma sp.", "Abronia cf. graminea"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := res[0].Taxonomy; a == nil || a.Genus != "Heloderma" || a.Species != "NA" || a.Family != "Helodermatidae" {
+		t.Errorf("Actual taxonomy for %s does not equal expected: Heloderma with species NA", res[0].Query)
+	}
+	if a := res[1]; a.Taxonomy == nil || a.Taxonomy.Species != "Abronia graminea" || a.Status != "unconfirmed" || a.Qualifier != "cf." {
+		t.Errorf("Actual result %v for %s does not equal expected: unconfirmed Abronia graminea", a.Record, a.Query)
+	}
+}
@@ -0,0 +1,396 @@
+// Defines interactive review of unconfirmed and missed search results
+
+package searchtaxa
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/icwells/go-tools/iotools"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"github.com/icwells/kestrel/src/taxonomy"
+	"github.com/icwells/simpleset"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var REVIEWHEADER = []string{"Query", "SearchTerm", "Decision", "Species"}
+
+type reviewer struct {
+	changed   bool
+	columns   map[string]int
+	confirmed int
+	decisions [][]string
+	done      *simpleset.Set
+	header    []string
+	ids       map[int]string
+	in        *bufio.Reader
+	levels    map[int]string
+	missed    [][]string
+	missfile  string
+	out       io.Writer
+	resfile   string
+	reviewed  string
+	rows      [][]string
+	searcher  *searcher
+	source    int
+}
+
+func newReviewer(r *Resolver, resfile string, in io.Reader, out io.Writer) (*reviewer, error) {
+	// Reads result, missed, and previous review files
+	rv := new(reviewer)
+	rv.done = simpleset.NewStringSet()
+	rv.ids = make(map[int]string)
+	rv.in = bufio.NewReader(in)
+	rv.levels = make(map[int]string)
+	rv.out = out
+	rv.resfile = resfile
+	rv.searcher = r.searcher.fork(nil)
+	dir, _ := path.Split(resfile)
+	rv.missfile = path.Join(dir, "KestrelMissed.csv")
+	rv.reviewed = path.Join(dir, "KestrelReviewed.csv")
+	if err := rv.readResults(); err != nil {
+		return nil, err
+	}
+	if iotools.Exists(rv.missfile) {
+		rows, err := readFile(rv.missfile)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range rows {
+			if len(i) < 2 {
+				i = append(i, i[0])
+			}
+			rv.missed = append(rv.missed, i)
+		}
+	}
+	if iotools.Exists(rv.reviewed) {
+		rows, err := readFile(rv.reviewed)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range rows {
+			if i[0] != "" {
+				rv.done.Add(i[0])
+			}
+		}
+	}
+	return rv, nil
+}
+
+func readRows(reader *kestrelutils.Reader, infile string) ([][]string, error) {
+	// Returns every row from reader; rows which cannot be parsed return an error since they could not be written back
+	var ret [][]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return ret, fmt.Errorf("cannot review %s: %v", infile, err)
+		}
+		ret = append(ret, row)
+	}
+	return ret, nil
+}
+
+func readFile(infile string) ([][]string, error) {
+	// Returns every row from file with a header
+	reader, err := kestrelutils.NewReader(infile, true)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return readRows(reader, infile)
+}
+
+func (rv *reviewer) readResults() error {
+	// Reads result file and stores column indeces
+	reader, err := kestrelutils.NewReader(rv.resfile, true)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	h := reader.Header
+	for _, i := range []string{"Query", "SearchTerm", "Source", "Confirmed"} {
+		if _, ex := h[i]; !ex {
+			return fmt.Errorf("%s column not found in %s (review requires csv output)", i, rv.resfile)
+		}
+	}
+	rv.columns = h
+	rv.header = reader.Names
+	rv.confirmed = h["Confirmed"]
+	rv.source = h["Source"]
+	// Write reviewed rows with the levels and identifiers of the result file
+	output := *rv.searcher.output
+	output.Identifiers, output.Levels = nil, nil
+	for idx := h["SearchTerm"] + 1; idx < rv.source; idx++ {
+		rv.levels[idx] = strings.ToLower(rv.header[idx])
+		output.Levels = append(output.Levels, rv.levels[idx])
+	}
+	for _, i := range kestrelutils.IDENTIFIERS {
+		if idx, ex := h[kestrelutils.IdentifierColumn(i)]; ex {
+			rv.ids[idx] = i
+			output.Identifiers = append(output.Identifiers, i)
+		}
+	}
+	_, cites := h["CITES"]
+	if cites && !output.CITES && rv.searcher.db != nil {
+		// Listings are only loaded by the resolver when CITES output was requested
+		output.SetCITES(rv.searcher.db.GetTable("Cites"))
+	}
+	output.CITES = cites
+	rv.searcher.output = &output
+	// Rows are kept as read so that only reviewed rows differ when the file is rewritten
+	rv.rows, err = readRows(reader, rv.resfile)
+	return err
+}
+
+func (rv *reviewer) padded(row []string) []string {
+	// Returns copy of row with missing columns filled with NA
+	ret := make([]string, len(row), len(rv.header))
+	copy(ret, row)
+	for len(ret) < len(rv.header) {
+		ret = append(ret, "NA")
+	}
+	return ret
+}
+
+func (rv *reviewer) setRow(row []string, t *taxonomy.Taxonomy) {
+	// Replaces every result column of row with values for taxonomy t and marks it as confirmed
+	// Annotations are recovered from the query
+	term := rv.searcher.options.Format(row[0])
+	// The chosen taxonomy replaces the parents of hybrid formulas
+	term.Hybrid, term.Parents = false, nil
+	term.Term = row[1]
+	term.Taxonomy = t
+	term.Confirm()
+	values := rv.searcher.row(term, row[0])
+	for idx, i := range rv.searcher.header() {
+		if c, ex := rv.columns[i]; ex && c < len(row) {
+			row[c] = values[idx]
+		}
+	}
+}
+
+func (rv *reviewer) current(row []string) string {
+	// Returns lineage and source stored in row
+	var ret []string
+	for idx := range rv.header {
+		if _, ex := rv.levels[idx]; ex && strings.ToUpper(row[idx]) != "NA" {
+			ret = append(ret, row[idx])
+		}
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(ret, " > "), row[rv.source])
+}
+
+func (rv *reviewer) alternatives(row []string) []*taxonomy.Taxonomy {
+	// Returns corpus candidates for search term and query which differ from the current species
+	var ret []*taxonomy.Taxonomy
+	seen := simpleset.NewStringSet()
+	seen.Add(rv.species(row))
+	for _, name := range []string{row[1], strings.TrimSpace(row[0])} {
+		for _, c := range rv.searcher.candidates(name, int(float64(len(name))*0.2), 5) {
+			if ex, _ := seen.InSet(c.key); !ex {
+				seen.Add(c.key)
+				ret = append(ret, rv.searcher.taxa[c.key])
+			}
+		}
+	}
+	return ret
+}
+
+func (rv *reviewer) enteredTaxonomy(name string) *taxonomy.Taxonomy {
+	// Returns corpus taxonomy for typed name or a new taxonomy filled from the hierarchy
+	t := taxonomy.NewTaxonomy()
+	if k := rv.searcher.corpusMatch(t.SpeciesCaps(name)); k != "" {
+		t.Copy(rv.searcher.taxa[k])
+	} else if k := rv.searcher.corpusMatch(name); k != "" {
+		t.Copy(rv.searcher.taxa[k])
+	} else {
+		t.Species = t.SpeciesCaps(name)
+		t.Genus = strings.Fields(t.Species)[0]
+		t.Source = "review"
+		rv.searcher.hier.FillTaxonomy(t)
+	}
+	return t
+}
+
+func (rv *reviewer) prompt(n, total int, row []string, unconfirmed bool) (*taxonomy.Taxonomy, string, bool) {
+	// Displays row and alternatives and returns chosen taxonomy, decision, and whether to quit
+	alt := rv.alternatives(row)
+	fmt.Fprintf(rv.out, "\n[%d/%d] Query: %s\n\tSearch term: %s\n", n, total, row[0], row[1])
+	options := "type a species name, skip (Enter), or quit (q)"
+	if unconfirmed {
+		fmt.Fprintf(rv.out, "\tCurrent: %s\n", rv.current(row))
+		options = "accept (a), " + options
+	} else {
+		fmt.Fprint(rv.out, "\tCurrent: no match\n")
+	}
+	if len(alt) > 0 {
+		fmt.Fprint(rv.out, "\tAlternatives:\n")
+		for idx, t := range alt {
+			fmt.Fprintf(rv.out, "\t\t%d) %s (%s)\n", idx+1, lineage(t), t.Source)
+		}
+		options = fmt.Sprintf("choose an alternative (1-%d), %s", len(alt), options)
+	}
+	for {
+		fmt.Fprintf(rv.out, "\t%s: ", strings.ToUpper(options[:1])+options[1:])
+		line, err := rv.in.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if err != nil && line == "" {
+			// Treat end of input as quit
+			return nil, "", true
+		}
+		switch strings.ToLower(line) {
+		case "":
+			return nil, "", false
+		case "q":
+			return nil, "", true
+		case "a":
+			if unconfirmed {
+				return nil, "accepted", false
+			}
+		default:
+			if idx, err := strconv.Atoi(line); err == nil {
+				if idx >= 1 && idx <= len(alt) {
+					return alt[idx-1], "alternative", false
+				}
+			} else if len(line) > 2 {
+				return rv.enteredTaxonomy(line), "entered", false
+			}
+		}
+		fmt.Fprintln(rv.out, "\tInvalid choice.")
+	}
+}
+
+func (rv *reviewer) species(row []string) string {
+	// Returns species stored in row
+	for idx, l := range rv.levels {
+		if l == "species" && idx < len(row) {
+			return row[idx]
+		}
+	}
+	return "NA"
+}
+
+func (rv *reviewer) record(row []string, decision string) {
+	// Stores decision for review file
+	rv.decisions = append(rv.decisions, []string{row[0], row[1], decision, rv.species(row)})
+	rv.done.Add(row[0])
+}
+
+func (rv *reviewer) pending() ([]int, []int) {
+	// Returns indeces of unconfirmed result rows and missed rows which have not been reviewed
+	var unconfirmed, missed []int
+	for idx, i := range rv.rows {
+		if i[0] == "" {
+			continue
+		} else if ex, _ := rv.done.InSet(i[0]); !ex && (len(i) <= rv.confirmed || i[rv.confirmed] != "yes") {
+			unconfirmed = append(unconfirmed, idx)
+		}
+	}
+	for idx, i := range rv.missed {
+		if ex, _ := rv.done.InSet(i[0]); !ex && i[0] != "" {
+			missed = append(missed, idx)
+		}
+	}
+	return unconfirmed, missed
+}
+
+func (rv *reviewer) review() {
+	// Steps through unconfirmed and missed rows
+	var n int
+	resolved := make(map[int]bool)
+	unconfirmed, missed := rv.pending()
+	total := len(unconfirmed) + len(missed)
+	for _, idx := range unconfirmed {
+		n++
+		row := rv.padded(rv.rows[idx])
+		t, decision, quit := rv.prompt(n, total, row, true)
+		if quit {
+			return
+		} else if t != nil {
+			rv.setRow(row, t)
+		} else if decision == "accepted" {
+			row[rv.confirmed] = "yes"
+		}
+		if decision != "" {
+			rv.rows[idx] = row
+			rv.changed = true
+			rv.record(row, decision)
+		}
+	}
+	defer func() {
+		// Remove resolved rows from missed
+		var rows [][]string
+		for idx, i := range rv.missed {
+			if !resolved[idx] {
+				rows = append(rows, i)
+			}
+		}
+		rv.missed = rows
+	}()
+	for _, idx := range missed {
+		n++
+		t, decision, quit := rv.prompt(n, total, rv.missed[idx], false)
+		if quit {
+			return
+		} else if t != nil {
+			row := rv.padded(rv.missed[idx])
+			rv.setRow(row, t)
+			rv.rows = append(rv.rows, row)
+			rv.changed = true
+			rv.record(row, decision)
+			resolved[idx] = true
+		}
+	}
+}
+
+func (rv *reviewer) save() error {
+	// Writes updated result and missed files and appends decisions to review file
+	if !rv.changed {
+		return nil
+	} else if err := kestrelutils.WriteCSV(rv.resfile, rv.header, rv.rows); err != nil {
+		return err
+	}
+	if iotools.Exists(rv.missfile) {
+		if err := kestrelutils.WriteCSV(rv.missfile, []string{"Query", "SearchTerm"}, rv.missed); err != nil {
+			return err
+		}
+	}
+	if len(rv.decisions) > 0 {
+		if !iotools.Exists(rv.reviewed) {
+			if err := kestrelutils.WriteCSV(rv.reviewed, REVIEWHEADER, nil); err != nil {
+				return err
+			}
+		}
+		w, err := kestrelutils.NewWriter(rv.reviewed, true)
+		if err != nil {
+			return err
+		}
+		for _, i := range rv.decisions {
+			w.Write(i)
+		}
+		return w.Close()
+	}
+	return nil
+}
+
+func Review(r *Resolver, resfile string, in io.Reader, out io.Writer) error {
+	// Interactively reviews unconfirmed and missed results and saves decisions
+	if err := kestrelutils.VerifyFile(resfile); err != nil {
+		return err
+	}
+	rv, err := newReviewer(r, resfile, in, out)
+	if err != nil {
+		return err
+	}
+	unconfirmed, missed := rv.pending()
+	fmt.Fprintf(rv.out, "Found %d unconfirmed and %d missed queries to review.\n", len(unconfirmed), len(missed))
+	rv.review()
+	if err = rv.save(); err == nil {
+		fmt.Fprintf(rv.out, "\nSaved %d decisions to %s.\n", len(rv.decisions), rv.reviewed)
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+// Tests interactive review
+
+package searchtaxa
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func writeReviewFiles(t *testing.T) string {
+	// Writes test result and missed files to temporary directory and returns path to result file
+	dir, err := ioutil.TempDir("", "kestrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := `Query,SearchTerm,Kingdom,Phylum,Class,Order,Family,Genus,Species,Source,Confirmed,CITES,Hybrid,Parents,Age
+gila monster x cricket,Gila monster,Animalia,Chordata,Reptilia,Squamata,Anguidae,Abronia,Abronia graminea,NCBI,no,yes,yes,Abronia graminea;Heloderma suspectum,
+cricket,Cricket,Animalia,Chordata,Insecta,Orthoptera,Gryllidae,Acheta,Acheta domesticus,NCBI,no,no,no,,
+,Unknown,NA,NA,NA,NA,NA,NA,NA,,no,no,no,,
+wolf,Wolf
+`
+	missed := "Query,SearchTerm\njuvenile acridotheres tristis,Acridotheres tristis\n"
+	ioutil.WriteFile(path.Join(dir, "searchResults.csv"), []byte(results), 0644)
+	ioutil.WriteFile(path.Join(dir, "KestrelMissed.csv"), []byte(missed), 0644)
+	return path.Join(dir, "searchResults.csv")
+}
+
+func TestReview(t *testing.T) {
+	resfile := writeReviewFiles(t)
+	dir, _ := path.Split(resfile)
+	defer os.RemoveAll(dir)
+	r := testResolver()
+	in := strings.NewReader("1\na\n\nAcridotheres tristis\n")
+	if err := Review(r, resfile, in, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	rows, _ := readFile(resfile)
+	exp := []string{"Heloderma suspectum", "Acheta domesticus", "", "", "Acridotheres tristis"}
+	if len(rows) != len(exp) {
+		t.Fatalf("Actual number of results %d does not equal expected: %d", len(rows), len(exp))
+	}
+	for idx, i := range rows {
+		if exp[idx] != "" && (i[8] != exp[idx] || i[10] != "yes" || i[11] != "no" || i[12] != "no" || i[13] != "") {
+			t.Errorf("Actual result %v does not equal expected: %s", i, exp[idx])
+		}
+	}
+	if rows[4][14] != "juvenile" {
+		t.Errorf("Actual annotation %s of resolved missed row does not equal expected: juvenile", rows[4][14])
+	}
+	// Rows which were not reviewed should be written as read
+	if rows[2][0] != "" || rows[2][1] != "Unknown" {
+		t.Errorf("Actual row %v without a query was not kept.", rows[2])
+	} else if len(rows[3]) != 2 || rows[3][0] != "wolf" {
+		t.Errorf("Actual skipped row %v does not equal expected: [wolf Wolf]", rows[3])
+	}
+	if missed, _ := readFile(path.Join(dir, "KestrelMissed.csv")); len(missed) != 0 {
+		t.Errorf("Actual number of missed rows %d does not equal expected: 0", len(missed))
+	}
+	reviewed, _ := readFile(path.Join(dir, "KestrelReviewed.csv"))
+	for idx, i := range []string{"alternative", "accepted", "entered"} {
+		if idx >= len(reviewed) || reviewed[idx][2] != i {
+			t.Errorf("Actual decision %d does not equal expected: %s", idx, i)
+		}
+	}
+	// Reviewed queries should not be presented again
+	rv, _ := newReviewer(r, resfile, strings.NewReader(""), ioutil.Discard)
+	if u, m := rv.pending(); len(u)+len(m) != 1 {
+		t.Errorf("Actual number of pending rows %d does not equal expected: 1", len(u)+len(m))
+	}
+	// Result file should not be rewritten without decisions
+	before, _ := ioutil.ReadFile(resfile)
+	if err := Review(r, resfile, strings.NewReader("q\n"), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := ioutil.ReadFile(resfile); string(after) != string(before) {
+		t.Error("Result file was rewritten without any decisions.")
+	}
+}
@@ -53,13 +53,9 @@ func (s *scorer) scoreLevel(t1, t2 string) int {
 func (s *scorer) score(t1, t2 *taxonomy.Taxonomy) int {
 	// Scores each taxonomy
 	ret := 0
-	ret += s.scoreLevel(t1.Kingdom, t2.Kingdom)
-	ret += s.scoreLevel(t1.Phylum, t2.Phylum)
-	ret += s.scoreLevel(t1.Class, t2.Class)
-	ret += s.scoreLevel(t1.Order, t2.Order)
-	ret += s.scoreLevel(t1.Family, t2.Family)
-	ret += s.scoreLevel(t1.Genus, t2.Genus)
-	ret += s.scoreLevel(t1.Species, t2.Species)
+	for _, i := range taxonomy.LEVELS {
+		ret += s.scoreLevel(t1.Get(i), t2.Get(i))
+	}
 	return ret
 }
 
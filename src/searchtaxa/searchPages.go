@@ -95,6 +95,7 @@ func (s *searcher) searchNCBI(k string) *taxonomy.Taxonomy {
 			if len(id) > 0 {
 				url := fmt.Sprintf("%sefetch.fcgi?db=Taxonomy&id=%s$retmode=xml&api_key=%s", s.urls.ncbi, id, s.keys["NCBI"])
 				ret.ScrapeNCBI(url)
+				ret.SetIdentifier("NCBI", id)
 			}
 		}
 	}
@@ -175,6 +176,7 @@ func (s *searcher) searchEOL(k string) *taxonomy.Taxonomy {
 				result, pass := getPage(url)
 				if pass == true {
 					ret.ScrapeEOL(result, url)
+					ret.SetIdentifier("EOL", tid)
 				}
 			}
 		}
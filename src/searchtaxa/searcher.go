@@ -3,14 +3,16 @@
 package searchtaxa
 
 import (
-	"fmt"
+	"encoding/json"
 	"github.com/icwells/dbIO"
 	"github.com/icwells/go-tools/iotools"
 	"github.com/icwells/kestrel/src/kestrelutils"
 	"github.com/icwells/kestrel/src/taxonomy"
 	"github.com/icwells/kestrel/src/terms"
 	"github.com/icwells/simpleset"
+	"io"
 	"log"
+	"os"
 	"path"
 	"strings"
 )
@@ -47,60 +49,102 @@ func newAPIs() *apis {
 //----------------------------------------------------------------------------
 
 type searcher struct {
-	common  map[string]string
-	corpus  bool
-	db      *dbIO.DBIO
-	done    *simpleset.Set
-	fails   int
-	hier    *taxonomy.Hierarchy
-	keys    map[string]string
-	logger  *log.Logger
-	matches int
-	missed  string
-	names   []string
-	outfile string
-	service *service
-	taxa    map[string]*taxonomy.Taxonomy
-	terms   map[string]*terms.Term
-	urls    *apis
-}
-
-func newSearcher(db *dbIO.DBIO, logger *log.Logger, outfile string, searchterms map[string]*terms.Term, nocorpus, test bool) searcher {
-	// Reads api keys and existing output and initializes maps
+	common    map[string]string
+	corpus    bool
+	db        *dbIO.DBIO
+	done      *simpleset.Set
+	fails     int
+	format    string
+	hier      *taxonomy.Hierarchy
+	homonyms  map[string][]*taxonomy.Taxonomy
+	index     *bktree
+	keys      map[string]string
+	logger    *log.Logger
+	matches   int
+	missed    string
+	options   *terms.Options
+	outfile   string
+	output    *taxonomy.Output
+	overrides *Overrides
+	service   *service
+	taxa      map[string]*taxonomy.Taxonomy
+	terms     map[string]*terms.Term
+	urls      *apis
+	web       bool
+}
+
+func newSearcher(db *dbIO.DBIO, logger *log.Logger, searchterms map[string]*terms.Term, options *terms.Options, nocorpus bool) searcher {
+	// Initializes maps and reads taxonomy corpus with common names in languages of options
 	var s searcher
 	s.corpus = !nocorpus
 	s.db = db
-	s.outfile = outfile
-	dir, _ := path.Split(s.outfile)
-	s.missed = path.Join(dir, "KestrelMissed.csv")
 	s.keys = make(map[string]string)
 	s.done = simpleset.NewStringSet()
 	s.logger = logger
+	s.options = options
+	s.output = taxonomy.NewOutput()
 	s.terms = searchterms
 	s.urls = newAPIs()
+	s.web = true
 	s.getCorpus()
-	if test == false {
-		s.service = newService()
-		s.apiKeys()
-		s.checkOutput(s.outfile, "Query,SearchTerm,Kingdom,Phylum,Class,Order,Family,Genus,Species,Source,Confirmed")
-		s.checkOutput(s.missed, "Query,SearchTerm")
-	}
 	return s
 }
 
+func (s *searcher) setOutput(outfile, format string) {
+	// Stores output files and reads existing output
+	s.format = format
+	s.outfile = outfile
+	dir, _ := path.Split(s.outfile)
+	s.missed = path.Join(dir, "KestrelMissed.csv")
+	s.checkOutput(s.outfile, s.header())
+	if !s.jsonl() {
+		// Misses are written to the result stream in json lines format
+		s.checkOutput(s.missed, []string{"Query", "SearchTerm"})
+	}
+}
+
+func (s *searcher) header() []string {
+	// Returns column names of result files
+	ret := append([]string{"Query", "SearchTerm"}, s.output.Header()...)
+	ret = append(append(ret, "Source", "Confirmed"), s.output.IdentifierHeader()...)
+	ret = append(append(ret, s.output.CITESHeader()...), terms.HybridHeader()...)
+	ret = append(append(ret, terms.QualifierHeader()...), terms.AuthorityHeader()...)
+	return append(ret, s.options.AnnotationHeader()...)
+}
+
+func (s *searcher) row(t *terms.Term, query string) []string {
+	// Returns result file row of t for query
+	ret := append([]string{query}, t.Slice(s.output)...)
+	return append(ret, t.AnnotationValues(s.options, query)...)
+}
+
 func (s *searcher) getCorpus() {
 	// Stores common name and taxonomy corpus
 	var taxa []*taxonomy.Taxonomy
 	common := make(map[string][]string)
+	ids := make(map[string][][]string)
+	levels := make(map[string][][]string)
 	s.common = make(map[string]string)
+	s.homonyms = make(map[string][]*taxonomy.Taxonomy)
 	set := simpleset.NewStringSet()
 	s.taxa = make(map[string]*taxonomy.Taxonomy)
 	for _, i := range s.db.GetTable("Common") {
+		if len(i) > 3 && !s.options.KeepLanguage(i[3]) {
+			// Skip common names in other languages
+			continue
+		}
 		if _, ex := common[i[0]]; !ex {
 			common[i[0]] = []string{}
 		}
 		common[i[0]] = append(common[i[0]], i[1])
 	}
+	for _, i := range s.db.GetTable("Levels") {
+		// Store intermediate and infraspecific levels by id
+		levels[i[0]] = append(levels[i[0]], i[1:])
+	}
+	for _, i := range s.db.GetTable("Identifiers") {
+		ids[i[0]] = append(ids[i[0]], i[1:])
+	}
 	for _, i := range s.db.GetTable("Taxonomy") {
 		id := i[0]
 		t := taxonomy.NewTaxonomy()
@@ -112,96 +156,188 @@ func (s *searcher) getCorpus() {
 		t.Genus = i[6]
 		t.Species = i[7]
 		t.Source = i[9]
+		if len(i) > 10 && i[10] != "" {
+			// Name every source which contributed to merged rows
+			t.Source = i[10]
+		}
 		if i[8] != "" {
 			// Add citation if available
 			t.Source += ": " + i[8]
 		}
+		for _, l := range levels[id] {
+			t.SetLevel(l[0], l[1])
+		}
+		for _, l := range ids[id] {
+			t.SetIdentifier(l[0], l[1])
+		}
+		// Store by lowest level so subspecies do not replace species
+		name := t.Name()
 		taxa = append(taxa, t)
-		s.taxa[t.Species] = t
-		set.Add(t.Species)
+		if prev, ex := s.taxa[name]; ex {
+			// Keep every taxonomy for names in more than one lineage so authorities can choose between them
+			if len(s.homonyms[name]) == 0 {
+				s.homonyms[name] = append(s.homonyms[name], prev)
+			}
+			s.homonyms[name] = append(s.homonyms[name], t)
+		}
+		s.taxa[name] = t
+		set.Add(name)
 		if v, ex := common[id]; ex {
-			for _, name := range v {
-				s.common[name] = t.Species
-				set.Add(name)
+			for _, c := range v {
+				s.common[c] = name
+				set.Add(c)
 			}
 		}
 	}
-	s.names = set.ToStringSlice()
+	// Index names for fuzzy matching
+	s.index = newBKTree(set.ToStringSlice())
 	s.hier = taxonomy.NewHierarchy(taxa)
 }
 
-func (s *searcher) assignKey(line string) {
-	// Assigns individual api key to struct
-	l := strings.Split(line, "=")
-	if len(l) == 2 {
-		s.keys[strings.TrimSpace(l[0])] = strings.TrimSpace(l[1])
-	}
+func (s *searcher) fork(searchterms map[string]*terms.Term) *searcher {
+	// Returns copy of searcher which shares the read-only corpus but not search terms or counts
+	ret := *s
+	ret.fails = 0
+	ret.matches = 0
+	ret.terms = searchterms
+	return &ret
 }
 
-func (s *searcher) apiKeys() {
-	// Reads api keys from file
-	infile := kestrelutils.GetAbsPath("API.txt")
-	kestrelutils.CheckFile(infile)
-	s.logger.Println("Reading API keys from file...")
-	f := iotools.OpenFile(infile)
+func APIKeys(infile string) (map[string]string, error) {
+	// Reads api keys from file of name=key lines
+	ret := make(map[string]string)
+	f, err := os.Open(infile)
+	if err != nil {
+		return ret, err
+	}
 	defer f.Close()
 	scanner := iotools.GetScanner(f)
 	for scanner.Scan() {
-		line := string(scanner.Text())
-		if line[0] != '#' {
-			s.assignKey(line)
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) > 0 && line[0] != '#' {
+			if l := strings.Split(line, "="); len(l) == 2 {
+				ret[strings.TrimSpace(l[0])] = strings.TrimSpace(l[1])
+			}
 		}
 	}
+	return ret, scanner.Err()
 }
 
-func (s *searcher) checkOutput(outfile, header string) {
+func (s *searcher) jsonl() bool {
+	// Returns true if output should be written as json lines
+	return s.format == "jsonl"
+}
+
+func (s *searcher) previousJSON(outfile string) {
+	// Stores queries from previous json lines output
+	out := iotools.OpenFile(outfile)
+	defer out.Close()
+	scanner := iotools.GetScanner(out)
+	for scanner.Scan() {
+		var r terms.Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err == nil && r.Query != "" {
+			s.done.Add(strings.TrimSpace(r.Query))
+		}
+	}
+}
+
+func (s *searcher) previousCSV(outfile string) {
+	// Stores queries from previous delimited output
+	reader, err := kestrelutils.NewReader(outfile, true)
+	if err != nil {
+		s.logger.Printf("[Error] Cannot read %s: %v\n", outfile, err)
+		return
+	}
+	defer reader.Close()
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil && !kestrelutils.IsParseError(err) {
+			s.logger.Printf("[Error] Cannot read %s: %v\n", outfile, err)
+			return
+		} else if err == nil && len(row) > 0 && row[0] != "" {
+			// Store queries (distinct lines)
+			s.done.Add(row[0])
+		}
+	}
+}
+
+func (s *searcher) checkOutput(outfile string, header []string) {
 	// Reads in completed searches
 	l := s.done.Length()
 	if iotools.Exists(outfile) == true {
-		var d string
-		first := true
 		s.logger.Printf("Reading previous output from %s\n", outfile)
-		out := iotools.OpenFile(outfile)
-		defer out.Close()
-		scanner := iotools.GetScanner(out)
-		for scanner.Scan() {
-			line := string(scanner.Text())
-			if first == false {
-				l := strings.Split(line, d)
-				// Store queries (distinct lines)
-				s.done.Add(strings.TrimSpace(l[0]))
-			} else {
-				d, _ = iotools.GetDelim(line)
-				first = false
-			}
+		if s.jsonl() {
+			s.previousJSON(outfile)
+		} else {
+			s.previousCSV(outfile)
 		}
 		s.logger.Printf("Found %d completed entries.\n", s.done.Length()-l)
 	} else {
 		s.logger.Println("Generating new output file...")
-		out := iotools.CreateFile(outfile)
-		defer out.Close()
-		out.WriteString(header + "\n")
+		if s.jsonl() {
+			// Json lines files do not have a header
+			header = nil
+		}
+		if err := kestrelutils.WriteCSV(outfile, header, nil); err != nil {
+			s.logger.Printf("[Error] Cannot create %s: %v\n", outfile, err)
+		}
+	}
+}
+
+func (s *searcher) appendRows(outfile string, rows [][]string) {
+	// Appends quoted rows to output file
+	w, err := kestrelutils.NewWriter(outfile, true)
+	if err != nil {
+		s.logger.Printf("[Error] Cannot write to %s: %v\n", outfile, err)
+		return
+	}
+	defer w.Close()
+	for _, i := range rows {
+		w.Write(i)
+	}
+}
+
+func (s *searcher) writeRecords(k string, found bool) {
+	// Appends json record for each query to output file
+	out := iotools.AppendFile(s.outfile)
+	defer out.Close()
+	for _, i := range s.terms[k].Queries {
+		out.WriteString(s.terms[k].JSON(i, found, s.output) + "\n")
+		if found {
+			s.matches++
+		} else {
+			s.fails++
+		}
 	}
 }
 
 func (s *searcher) writeMisses(k string) {
 	// Writes terms with no match to missed file
-	out := iotools.AppendFile(s.missed)
-	defer out.Close()
+	if s.jsonl() {
+		s.writeRecords(k, false)
+		return
+	}
+	var rows [][]string
 	t := kestrelutils.PercentDecode(k)
 	for _, i := range s.terms[k].Queries {
-		out.WriteString(fmt.Sprintf("%s,%s\n", i, t))
+		rows = append(rows, []string{i, t})
 		s.fails++
 	}
+	s.appendRows(s.missed, rows)
 }
 
 func (s *searcher) writeMatches(k string) {
 	// Appends matches to file
-	out := iotools.AppendFile(s.outfile)
-	defer out.Close()
-	match := s.terms[k].String()
+	if s.jsonl() {
+		s.writeRecords(k, true)
+		return
+	}
+	var rows [][]string
 	for _, i := range s.terms[k].Queries {
-		out.WriteString(fmt.Sprintf("%s,%s\n", i, match))
+		rows = append(rows, s.row(s.terms[k], i))
 		s.matches++
 	}
+	s.appendRows(s.outfile, rows)
 }
@@ -12,7 +12,7 @@ import (
 	"strings"
 )
 
-func (s *searcher) parseURLs(urls map[string]string) map[string]*taxonomy.Taxonomy {
+func (s *searcher) parseURLs(urls map[string]string, blocked map[string]bool) map[string]*taxonomy.Taxonomy {
 	// Attempts to find taxonomy from given urls
 	taxa := make(map[string]*taxonomy.Taxonomy)
 	for k, v := range urls {
@@ -31,7 +31,7 @@ func (s *searcher) parseURLs(urls map[string]string) map[string]*taxonomy.Taxono
 		case s.urls.adw:
 			t.ScrapeAnimalDiversityWeb(k)
 		}
-		if t.Found == true {
+		if t.Found == true && !blocked[strings.ToLower(t.Species)] {
 			taxa[t.Source] = t
 		}
 	}
@@ -85,12 +85,12 @@ func (s *searcher) seleniumSearch(k string) string {
 	return ret
 }
 
-func (s *searcher) getSearchResults(k string) bool {
+func (s *searcher) getSearchResults(k string, blocked map[string]bool) bool {
 	// Parses urls from google search results
 	found := false
 	res := s.seleniumSearch(k)
 	urls := s.getURLs(res)
-	taxa := s.parseURLs(urls)
+	taxa := s.parseURLs(urls, blocked)
 	if len(taxa) >= 1 {
 		// Only attempt getMatch once
 		found = s.getMatch(s.terms[k].Term, taxa)
@@ -0,0 +1,121 @@
+// Defines http server for resolving names against the taxonomy corpus
+
+package searchtaxa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+type batch struct {
+	Names []string `json:"names"`
+}
+
+type server struct {
+	logger   *log.Logger
+	resolver *Resolver
+	timeout  time.Duration
+}
+
+func newServer(resolver *Resolver, timeout time.Duration, logger *log.Logger) *server {
+	// Initializes struct
+	s := new(server)
+	s.logger = logger
+	s.resolver = resolver
+	s.timeout = timeout
+	return s
+}
+
+func (s *server) resolveNames(r *http.Request, names []string) []Result {
+	// Resolves names within the request timeout; searches which exceed the deadline are marked as timed out
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+	ret, err := s.resolver.Resolve(ctx, names)
+	if err != nil {
+		s.logger.Printf("Request for %d names ended early: %v\n", len(names), err)
+	}
+	return ret
+}
+
+func (s *server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	// Writes v as json response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.Printf("[Error] Cannot write response: %v\n", err)
+	}
+}
+
+func (s *server) writeError(w http.ResponseWriter, status int, msg string) {
+	// Writes error message as json response
+	s.writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func (s *server) health(w http.ResponseWriter, r *http.Request) {
+	// Reports server status and corpus size
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "ok",
+		"taxa":   len(s.resolver.searcher.taxa),
+	})
+}
+
+func (s *server) resolve(w http.ResponseWriter, r *http.Request) {
+	// Resolves single name given by name parameter
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "use GET /resolve?name=")
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		s.writeError(w, http.StatusBadRequest, "name parameter is required")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.resolveNames(r, []string{name})[0])
+}
+
+func (s *server) batch(w http.ResponseWriter, r *http.Request) {
+	// Resolves names given as json list
+	var b batch
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "use POST /batch")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot parse request: %v", err))
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.resolveNames(r, b.Names))
+}
+
+func (s *server) handler() http.Handler {
+	// Returns request multiplexer
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.health)
+	mux.HandleFunc("/resolve", s.resolve)
+	mux.HandleFunc("/batch", s.batch)
+	// OpenRefine reconciliation service
+	mux.HandleFunc("/reconcile", s.reconcile)
+	mux.HandleFunc("/reconcile/preview", s.preview)
+	mux.HandleFunc("/reconcile/properties", s.proposeProperties)
+	mux.HandleFunc("/reconcile/suggest/entity", s.suggestEntity)
+	mux.HandleFunc("/reconcile/suggest/property", s.suggestProperty)
+	mux.HandleFunc("/reconcile/suggest/type", s.suggestType)
+	return mux
+}
+
+func Serve(resolver *Resolver, port int, timeout time.Duration, logger *log.Logger) error {
+	// Serves resolution endpoints until the listener fails
+	s := newServer(resolver, timeout, logger)
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", port),
+		Handler:      s.handler(),
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout + 5*time.Second,
+	}
+	s.logger.Printf("Serving %d taxonomies on port %d...\n", len(resolver.searcher.taxa), port)
+	return srv.ListenAndServe()
+}
@@ -0,0 +1,70 @@
+// Tests http server handlers
+
+package searchtaxa
+
+import (
+	"encoding/json"
+	"github.com/icwells/kestrel/src/terms"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testServer() *server {
+	// Returns server with in-memory corpus
+	r := testResolver()
+	return newServer(r, time.Second, r.logger)
+}
+
+func TestResolve(t *testing.T) {
+	var r terms.Record
+	s := testServer()
+	w := httptest.NewRecorder()
+	s.handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/resolve?name=GILA+MONSTER", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Actual status %d does not equal expected: %d", w.Code, http.StatusOK)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Ranks["species"] != "Heloderma suspectum" {
+		t.Errorf("Actual species %s does not equal expected: Heloderma suspectum", r.Ranks["species"])
+	}
+	if r.Confidence != 1.0 {
+		t.Errorf("Actual confidence %f does not equal expected: 1.0", r.Confidence)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	var r []terms.Record
+	s := testServer()
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"names": ["cricket", "Acridotheres tristis", "Cricket", "Zebra finch"]}`)
+	s.handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/batch", body))
+	if err := json.Unmarshal(w.Body.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"Acheta domesticus", "Acridotheres tristis", "Acheta domesticus", ""}
+	if len(r) != len(exp) {
+		t.Fatalf("Actual number of records %d does not equal expected: %d", len(r), len(exp))
+	}
+	for idx, i := range r {
+		if i.Ranks["species"] != exp[idx] {
+			t.Errorf("Actual species %s for %s does not equal expected: %s", i.Ranks["species"], i.Query, exp[idx])
+		}
+	}
+	if r[3].Status != "missed" {
+		t.Errorf("Actual status %s does not equal expected: missed", r[3].Status)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s := testServer()
+	w := httptest.NewRecorder()
+	s.handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ok"`) {
+		t.Errorf("Actual health response %s does not equal expected: ok", w.Body.String())
+	}
+}
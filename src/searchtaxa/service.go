@@ -33,6 +33,11 @@ func newService() *service {
 	return s
 }
 
+func (s *service) running() bool {
+	// Returns true if service was started without error
+	return s != nil && s.err == nil
+}
+
 func (s *service) getBrowser() (selenium.WebDriver, error) {
 	// Returns browser instance and error
 	caps := selenium.Capabilities{"browserName": s.browser,
@@ -46,16 +51,15 @@ func (s *service) getBrowser() (selenium.WebDriver, error) {
 
 func (s *service) startService() {
 	// Initialzes new selenium service
-	var err error
 	dir := path.Join(iotools.GetGOPATH(), "src/github.com/tebeka/selenium/vendor")
 	opts := []selenium.ServiceOption{
 		selenium.StartFrameBuffer(),
 		selenium.Output(s.log),
 		selenium.ChromeDriver(path.Join(dir, "chromedriver")),
 	}
-	s.service, err = selenium.NewSeleniumService(path.Join(dir, "selenium-server.jar"), s.port, opts...)
-	if err != nil {
-		fmt.Println(err)
+	s.service, s.err = selenium.NewSeleniumService(path.Join(dir, "selenium-server.jar"), s.port, opts...)
+	if s.err != nil {
+		fmt.Println(s.err)
 	}
 }
 
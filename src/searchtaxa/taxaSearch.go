@@ -3,18 +3,15 @@
 package searchtaxa
 
 import (
+	"context"
 	"fmt"
-	"github.com/icwells/dbIO"
 	"github.com/icwells/kestrel/src/kestrelutils"
 	"github.com/icwells/kestrel/src/taxonomy"
 	"github.com/icwells/kestrel/src/terms"
-	"github.com/lithammer/fuzzysearch/fuzzy"
-	"log"
+	"math"
 	"os"
 	"sort"
 	"strings"
-	"sync"
-	"time"
 )
 
 func (s *searcher) setTaxonomy(k, key string, t map[string]*taxonomy.Taxonomy) {
@@ -24,6 +21,24 @@ func (s *searcher) setTaxonomy(k, key string, t map[string]*taxonomy.Taxonomy) {
 		s.hier.FillTaxonomy(t[key])
 	}
 	s.terms[k].Taxonomy.Copy(t[key])
+	s.terms[k].Sources = nil
+	for source, v := range t {
+		if v.Species == t[key].Species {
+			// Keep identifiers from each source which agrees on species
+			s.terms[k].Taxonomy.MergeIdentifiers(v)
+			s.terms[k].Sources = append(s.terms[k].Sources, source)
+		}
+	}
+	sort.Strings(s.terms[k].Sources)
+}
+
+func webConfidence(score, nas int) float64 {
+	// Returns proportion of agreeing levels between best pair, or half of completeness for single sources
+	l := float64(len(taxonomy.MAJOR))
+	if score > 0 {
+		return math.Min(float64(score)/l, 1.0)
+	}
+	return 0.5 * (l - float64(nas)) / l
 }
 
 func (s *searcher) getMatch(k string, taxa map[string]*taxonomy.Taxonomy) bool {
@@ -60,6 +75,7 @@ func (s *searcher) getMatch(k string, taxa map[string]*taxonomy.Taxonomy) bool {
 	}
 	if len(key) > 0 {
 		s.setTaxonomy(k, key, taxa)
+		s.terms[k].Confidence = webConfidence(score, s.terms[k].Taxonomy.Nas)
 		if score >= 7 || strings.ToLower(s.terms[k].Taxonomy.Species) == strings.ToLower(k) {
 			s.terms[k].Confirm()
 		} else if s.corpusMatch(k) != "" {
@@ -70,24 +86,22 @@ func (s *searcher) getMatch(k string, taxa map[string]*taxonomy.Taxonomy) bool {
 	return ret
 }
 
-func checkMatch(taxa map[string]*taxonomy.Taxonomy, t *taxonomy.Taxonomy) map[string]*taxonomy.Taxonomy {
-	// Appends t to taxonomy if a match was found
-	if t.Found && t.Nas <= 2 {
+func checkMatch(taxa map[string]*taxonomy.Taxonomy, t *taxonomy.Taxonomy, blocked map[string]bool) map[string]*taxonomy.Taxonomy {
+	// Appends t to taxonomy if a match was found and species has not been blocked
+	if t.Found && t.Nas <= 2 && !blocked[strings.ToLower(t.Species)] {
 		taxa[t.Source] = t
 	}
 	return taxa
 }
 
-func (s *searcher) writeResults(mut *sync.RWMutex, k string, found bool) {
-	// Manages mutex call and writes to appropriate output file
-	mut.Lock()
+func (s *searcher) writeResult(k string, found bool) {
+	// Writes result to appropriate output file
 	if found == true {
 		s.writeMatches(k)
 	} else {
 		// Write missed queries to file
 		s.writeMisses(k)
 	}
-	mut.Unlock()
 }
 
 func (s *searcher) corpusMatch(name string) string {
@@ -101,56 +115,107 @@ func (s *searcher) corpusMatch(name string) string {
 	return ""
 }
 
-func (s *searcher) searchCorpus(t *terms.Term) bool {
-	// Compares search term to existing taxonomy corpus
-	if k := s.corpusMatch(t.Term); k != "" {
-		t.Taxonomy.Copy(s.taxa[k])
-		t.Confirmed = true
-		return true
-	} else {
-		// Attempt to find fuzzy match
-		matches := fuzzy.RankFindFold(t.Term, s.names)
-		if matches.Len() > 0 {
-			sort.Sort(matches)
-			if matches[0].Distance <= int(float64(len(t.Term))*0.1) {
-				if k := s.corpusMatch(matches[0].Target); k != "" {
-					t.Taxonomy.Copy(s.taxa[k])
-					return true
+type candidate struct {
+	exact bool
+	key   string
+	score float64
+}
+
+func (s *searcher) candidates(name string, max, limit int) []candidate {
+	// Returns up to limit corpus keys for exact and fuzzy matches to name in order of score
+	var ret []candidate
+	seen := make(map[string]bool)
+	if k := s.corpusMatch(name); k != "" {
+		ret = append(ret, candidate{true, k, 1.0})
+		seen[k] = true
+	}
+	if len(ret) < limit {
+		for _, i := range s.index.search(name, max) {
+			if k := s.corpusMatch(i.Target); k != "" && !seen[k] {
+				ret = append(ret, candidate{false, k, math.Max(0.0, 1.0-float64(i.Distance)/float64(len(name)))})
+				seen[k] = true
+				if len(ret) >= limit {
+					break
 				}
 			}
 		}
 	}
+	return ret
+}
+
+func (s *searcher) homonym(t *terms.Term, key string) *taxonomy.Taxonomy {
+	// Returns corpus taxonomy for key, using the term's authority to choose between homonyms
+	if v := t.Name.Choose(s.homonyms[key]); v != nil {
+		return v
+	}
+	return s.taxa[key]
+}
+
+func (s *searcher) searchCorpus(t *terms.Term, blocked map[string]bool) bool {
+	// Compares search term to existing taxonomy corpus
+	limit := 1 + len(blocked)
+	for _, c := range s.candidates(t.Term, int(float64(len(t.Term))*0.1), limit) {
+		if x := s.homonym(t, c.key); !blocked[strings.ToLower(x.Species)] {
+			t.Taxonomy.Copy(x)
+			t.Confirmed = c.exact
+			t.Confidence = c.score
+			t.Sources = nil
+			return true
+		}
+	}
 	return false
 }
 
+func (s *searcher) searchGenus(t *terms.Term) bool {
+	// Resolves genus-level terms from the lineages of corpus taxa
+	x := taxonomy.NewTaxonomy()
+	x.SetLevel("genus", t.Term)
+	s.hier.FillTaxonomy(x)
+	if x.Kingdom == "NA" {
+		return false
+	}
+	x.Found = true
+	t.Taxonomy.Copy(x)
+	t.Confirmed = true
+	t.Confidence = 1.0
+	t.Sources = nil
+	return true
+}
+
 func (s *searcher) wordCount(k string) int {
 	// Returns number of words
 	return strings.Count(s.terms[k].Term, kestrelutils.SPACE) + 1
 }
 
-func (s *searcher) dispatchTerm(k string) bool {
-	// Performs api search for given term
+func (s *searcher) dispatchTerm(ctx context.Context, k string) bool {
+	// Performs api search for given term until it is found or ctx is done
 	var found bool
+	blocked := s.overrides.blockedSpecies(k, s.terms[k])
 	for !found {
+		if ctx.Err() != nil {
+			// Reset term and stop searching once ctx is done
+			s.terms[k].Term = k
+			break
+		}
 		l := s.wordCount(k)
 		if s.corpus {
-			found = s.searchCorpus(s.terms[k])
+			found = s.searchCorpus(s.terms[k], blocked)
 		}
-		if !found {
+		if !found && s.web {
 			taxa := make(map[string]*taxonomy.Taxonomy)
-			// Search IUCN, NCBI, Wikipedia, and EOL
-			taxa = checkMatch(taxa, s.searchIUCN(k))
-			taxa = checkMatch(taxa, s.searchNCBI(k))
-			taxa = checkMatch(taxa, s.searchEOL(k))
-			taxa = checkMatch(taxa, s.searchWikipedia(k))
-			taxa = checkMatch(taxa, s.searchWikiSpecies(k))
-			if len(taxa) >= 1 {
+			// Search IUCN, NCBI, EOL, Wikipedia, and Wikispecies while ctx is active
+			for _, search := range []func(string) *taxonomy.Taxonomy{s.searchIUCN, s.searchNCBI, s.searchEOL, s.searchWikipedia, s.searchWikiSpecies} {
+				if ctx.Err() == nil {
+					taxa = checkMatch(taxa, search(k), blocked)
+				}
+			}
+			if len(taxa) >= 1 && ctx.Err() == nil {
 				found = s.getMatch(k, taxa)
 			}
 		}
-		if !found && s.service.err == nil {
+		if !found && s.web && s.service.running() && ctx.Err() == nil {
 			// Perform selenium search if service is running
-			found = s.getSearchResults(k)
+			found = s.getSearchResults(k, blocked)
 		}
 		if !found && !s.terms[k].Scientific && l != 1 {
 			// Remove first word and try again
@@ -166,24 +231,43 @@ func (s *searcher) dispatchTerm(k string) bool {
 	return found
 }
 
-func (s *searcher) searchTerm(wg *sync.WaitGroup, mut *sync.RWMutex, k string) {
-	// Performs api search for given and corrected term
-	defer wg.Done()
+func (s *searcher) findHybrid(ctx context.Context, k string) bool {
+	// Searches each parent of a hybrid term and stores their lowest common taxonomy
+	for _, p := range s.terms[k].Parents {
+		if !s.fork(map[string]*terms.Term{p.Term: p}).findTerm(ctx, p.Term) {
+			return false
+		}
+	}
+	s.terms[k].SetHybrid()
+	// Parents without a common kingdom are not a match
+	return s.terms[k].Taxonomy.Found
+}
+
+func (s *searcher) findTerm(ctx context.Context, k string) bool {
+	// Performs search for given and corrected term until ctx is done
 	var found bool
+	if s.terms[k].Hybrid {
+		return s.findHybrid(ctx, k)
+	} else if s.terms[k].GenusOnly() && s.corpus && s.searchGenus(s.terms[k]) {
+		return true
+	}
 	for idx, i := range []string{s.terms[k].Term, s.terms[k].Corrected} {
-		if !found && len(i) > 0 {
+		if !found && len(i) > 0 && ctx.Err() == nil {
 			if !s.terms[k].Scientific && idx == 1 {
 				// Set corrected term as term
 				s.terms[k].Term, s.terms[k].Corrected = s.terms[k].Corrected, s.terms[k].Term
 			}
-			found = s.dispatchTerm(k)
+			found = s.dispatchTerm(ctx, k)
 			if !s.terms[k].Scientific && idx == 1 && !found {
 				// Reset original search term
 				s.terms[k].Term, s.terms[k].Corrected = s.terms[k].Corrected, s.terms[k].Term
 			}
 		}
 	}
-	s.writeResults(mut, k, found)
+	if found {
+		s.terms[k].Qualify()
+	}
+	return found
 }
 
 func (s *searcher) searchDone() {
@@ -202,41 +286,41 @@ func (s *searcher) searchDone() {
 	}
 }
 
-func SearchTaxonomies(db *dbIO.DBIO, outfile string, searchterms map[string]*terms.Term, proc int, nocorpus bool, logger *log.Logger) {
-	// Manages API and selenium searches
-	var wg sync.WaitGroup
-	var mut sync.RWMutex
-	count := 1
-	s := newSearcher(db, logger, outfile, searchterms, nocorpus, false)
-	if s.service.err == nil {
-		defer s.service.stop()
+func SearchTaxonomies(c Config, outfile, format string, searchterms map[string]*terms.Term) {
+	// Manages API and selenium searches with given database, output, and search settings
+	var count int
+	var err error
+	c.Keys, err = APIKeys(kestrelutils.GetAbsPath("API.txt"))
+	if err != nil {
+		c.Logger.Printf("[Error] Cannot read API keys: %v\n", err)
+		os.Exit(1)
+	}
+	c.Selenium = true
+	c.Web = true
+	r, err := NewResolver(c)
+	if err != nil {
+		c.Logger.Printf("[Error] %v\n", err)
+		os.Exit(1)
 	}
+	defer r.Close()
+	s := r.searcher.fork(searchterms)
+	s.setOutput(outfile, format)
 	// Concurrently perform api search
 	fmt.Println()
 	s.logger.Println("Performing taxonomy search...")
 	s.searchDone()
+	if n := s.applyOverrides(s.writeResult); n > 0 {
+		s.logger.Printf("Applied overrides to %d terms.\n", n)
+	}
 	if len(s.terms) > 0 {
 		s.logger.Println("Performing API search...")
-		for k := range s.terms {
-			wg.Add(1)
-			go s.searchTerm(&wg, &mut, k)
-			fmt.Printf("\tDispatched %d of %d terms.\r", count, len(s.terms))
-			if count%10 == 0 {
-				// Pause after 10 to avoid swamping apis
-				time.Sleep(time.Second)
-			}
-			if count%proc == 0 {
-				// Pause to avoid using all available RAM
-				wg.Wait()
-			}
+		r.search(context.Background(), s, func(k string, found bool) {
+			s.writeResult(k, found)
 			count++
-		}
-		// Wait for remainging processes
-		s.logger.Println("Waiting for search results...")
-		wg.Wait()
+			fmt.Printf("\tCompleted %d of %d terms.\r", count, len(s.terms))
+		})
 	}
 	fmt.Println()
-	s.service.KillChromeDrivers()
 	s.logger.Printf("Found matches for a total of %d queries.\n", s.matches)
 	s.logger.Printf("Could not find matches for %d queries.\n", s.fails)
 	if s.fails == 0 {
@@ -48,7 +48,7 @@ func getTestSearcher() searcher {
 		t.Term = i[1]
 		exp[i[1]] = t
 	}
-	s := newSearcher(db, kestrelutils.GetLogger(), "", exp, true, true)
+	s := newSearcher(db, kestrelutils.GetLogger(), exp, terms.NewOptions(), true)
 	return s
 }
 
@@ -72,7 +72,7 @@ func TestCheckMatch(t *testing.T) {
 	s := taxaSlice()
 	for idx, i := range s {
 		k := strconv.Itoa(idx)
-		taxa := checkMatch(taxa, i)
+		taxa := checkMatch(taxa, i, nil)
 		_, ex := taxa[k]
 		if i.Found == false || i.Nas > 2 {
 			if ex == true {
@@ -90,3 +90,16 @@ func TestCheckMatch(t *testing.T) {
 		t.Errorf("Count of checkMatch output is incorrect.")
 	}
 }
+
+func TestHomonym(t *testing.T) {
+	s := taxaSlice()
+	s[0].Source, s[1].Source = "Peters, 1863", "Peters, 1863"
+	searcher := getTestSearcher()
+	searcher.taxa = map[string]*taxonomy.Taxonomy{"Abronia graminea": s[1]}
+	searcher.homonyms = map[string][]*taxonomy.Taxonomy{"Abronia graminea": {s[0], s[1]}}
+	term := terms.NewTerm("Abronia graminea Peters, 1863")
+	term.Name = terms.ParseName(term.Queries[0])
+	if v := searcher.homonym(term, "Abronia graminea"); v != s[1] {
+		t.Errorf("Tied homonym did not default to corpus taxonomy.")
+	}
+}
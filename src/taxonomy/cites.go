@@ -0,0 +1,47 @@
+// Defines CITES listing lookups and output configuration
+
+package taxonomy
+
+import (
+	"strings"
+)
+
+func (o *Output) SetCITES(rows [][]string) {
+	// Stores listed names from Cites table rows (ID, Name, Authority)
+	o.listed = make(map[string]string)
+	for _, i := range rows {
+		if len(i) >= 3 {
+			o.listed[strings.ToLower(i[1])] = i[2]
+		} else if len(i) == 2 {
+			o.listed[strings.ToLower(i[1])] = ""
+		}
+	}
+}
+
+func (o *Output) CITESHeader() []string {
+	// Returns CITES column name if it is written to output
+	if o.CITES {
+		return []string{"CITES"}
+	}
+	return nil
+}
+
+func (o *Output) IsCITES(t *Taxonomy) bool {
+	// Returns true if the lowest level or species of t is CITES-listed
+	for _, i := range []string{t.Name(), t.Species} {
+		if _, ex := o.listed[strings.ToLower(i)]; ex && !isNA(i) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Output) CITESValues(t *Taxonomy) []string {
+	// Returns CITES listing of t for output
+	if !o.CITES {
+		return nil
+	} else if o.IsCITES(t) {
+		return []string{"yes"}
+	}
+	return []string{"no"}
+}
@@ -0,0 +1,53 @@
+// Tests CITES checklist loading and listing output
+
+package taxonomy
+
+import (
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"testing"
+)
+
+func TestLoadCITES(t *testing.T) {
+	u := newUploader(nil, 10, kestrelutils.GetLogger())
+	u.names["Antilocapra americana"] = "1"
+	u.tid = 2
+	listed, err := u.readCITES("../../utils/citesAnimalia.csv.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 5981 || len(u.taxa) != len(listed)-1 {
+		t.Errorf("Actual number of CITES taxa %d (%d new) does not equal expected: 5981 (5980 new)", len(listed), len(u.taxa))
+	}
+	for _, i := range listed {
+		if i.Get("subspecies") == "Capra hircus aegagrus" && i.Species != "Capra hircus" {
+			t.Errorf("Actual species %s does not equal expected: Capra hircus", i.Species)
+		}
+	}
+	u.fillTaxonomies(CITES)
+	u.setRows()
+	table := u.citesTable(listed)
+	if len(table) != len(listed) || table[0][0] != "1" || table[0][2] != "Ord, 1815" {
+		t.Errorf("Actual first CITES row %v does not equal expected: [1 Antilocapra americana Ord, 1815]", table[0])
+	}
+	for _, i := range u.res {
+		if i[len(i)-1] != CITES {
+			t.Errorf("Actual DB value %s does not equal expected: %s", i[len(i)-1], CITES)
+			break
+		}
+	}
+}
+
+func TestCITESValues(t *testing.T) {
+	o := NewOutput()
+	o.CITES = true
+	o.SetCITES([][]string{{"1", "Heloderma suspectum", "Cope, 1869"}})
+	for _, i := range taxaSlice() {
+		exp := "no"
+		if i.Species == "Heloderma suspectum" {
+			exp = "yes"
+		}
+		if v := o.CITESValues(i); len(v) != 1 || v[0] != exp {
+			t.Errorf("Actual CITES value %v for %s does not equal expected: %s", v, i.Species, exp)
+		}
+	}
+}
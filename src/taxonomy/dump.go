@@ -0,0 +1,204 @@
+// Writes filtered corpus tables and a joined species view
+
+package taxonomy
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/icwells/dbIO"
+	"github.com/icwells/go-tools/strarray"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"log"
+	"path"
+	"sort"
+	"strings"
+)
+
+// SPECIES is the name of the joined view of taxonomies and their common names
+var SPECIES = "Species"
+
+type DumpOptions struct {
+	Clades []string // Level=Name pairs which every written taxonomy must match
+	Format string   // csv or jsonl
+	Gzip   bool     // Compress output files
+	Outdir string   // Output directory
+	Tables []string // Tables to write (including Species); every table is written if empty
+}
+
+func parseClades(clades []string) (map[string]string, error) {
+	// Returns clade names by lower case level
+	ret := make(map[string]string)
+	for _, i := range clades {
+		s := strings.SplitN(i, "=", 2)
+		if len(s) != 2 {
+			return ret, fmt.Errorf("clade %s is not formatted as Level=Name", i)
+		}
+		level := strings.ToLower(strings.TrimSpace(s[0]))
+		if level == "orders" {
+			level = "order"
+		}
+		if !strarray.InSliceStr(LEVELS, level) {
+			return ret, fmt.Errorf("%s is not a recognized taxonomic level", s[0])
+		}
+		ret[level] = strings.TrimSpace(s[1])
+	}
+	return ret, nil
+}
+
+func cladeIDs(taxa map[string]*Taxonomy, clades map[string]string) map[string]bool {
+	// Returns ids of taxonomies which match every clade
+	ret := make(map[string]bool)
+	for k, t := range taxa {
+		keep := true
+		for level, name := range clades {
+			if !strings.EqualFold(t.Get(level), name) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			ret[k] = true
+		}
+	}
+	return ret
+}
+
+type dumper struct {
+	columns map[string][]string
+	ids     map[string]bool
+	logger  *log.Logger
+	opt     DumpOptions
+}
+
+func (d *dumper) filter(rows [][]string) [][]string {
+	// Returns rows with a kept id
+	if d.ids == nil {
+		return rows
+	}
+	var ret [][]string
+	for _, i := range rows {
+		if len(i) > 0 && d.ids[i[0]] {
+			ret = append(ret, i)
+		}
+	}
+	return ret
+}
+
+func speciesView(taxa, common [][]string) ([]string, [][]string) {
+	// Returns header and rows of taxonomies joined with their common names
+	names := make(map[string][]string)
+	for _, i := range common {
+		names[i[0]] = append(names[i[0]], i[1])
+	}
+	header := []string{"ID"}
+	for _, i := range MAJOR {
+		header = append(header, strarray.TitleCase(i))
+	}
+	header = append(header, "Citation", "DB", "Common")
+	var rows [][]string
+	for _, i := range taxa {
+		if len(i) > len(MAJOR)+2 {
+			sort.Strings(names[i[0]])
+			rows = append(rows, append(i[:len(MAJOR)+3:len(MAJOR)+3], strings.Join(names[i[0]], ";")))
+		}
+	}
+	return header, rows
+}
+
+func (d *dumper) outfile(table string) string {
+	// Returns output path for table
+	ret := path.Join(d.opt.Outdir, table+"."+d.opt.Format)
+	if d.opt.Gzip {
+		ret += ".gz"
+	}
+	return ret
+}
+
+func writeJSONL(outfile string, header []string, rows [][]string) error {
+	// Writes each row as a json object keyed by header
+	out, err := kestrelutils.NewOutput(outfile, false)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out)
+	for _, i := range rows {
+		obj := make(map[string]string)
+		for idx, h := range header {
+			if idx < len(i) {
+				obj[h] = i[idx]
+			}
+		}
+		if err = enc.Encode(obj); err != nil {
+			break
+		}
+	}
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (d *dumper) write(table string, header []string, rows [][]string) error {
+	// Writes table in the selected format
+	outfile := d.outfile(table)
+	d.logger.Printf("Saving %d rows from %s to %s...\n", len(rows), table, outfile)
+	if d.opt.Format == "jsonl" {
+		return writeJSONL(outfile, header, rows)
+	}
+	return kestrelutils.WriteCSV(outfile, header, rows)
+}
+
+func (d *dumper) tables() ([]string, error) {
+	// Returns selected tables (every corpus table and the species view by default)
+	if len(d.opt.Tables) == 0 {
+		ret := []string{SPECIES}
+		for k := range d.columns {
+			ret = append(ret, k)
+		}
+		sort.Strings(ret)
+		return ret, nil
+	}
+	var ret []string
+	for _, i := range d.opt.Tables {
+		i = strings.TrimSpace(i)
+		if _, ex := d.columns[i]; !ex && i != SPECIES {
+			return nil, fmt.Errorf("%s is not a corpus table", i)
+		}
+		ret = append(ret, i)
+	}
+	return ret, nil
+}
+
+func Dump(db *dbIO.DBIO, opt DumpOptions, logger *log.Logger) error {
+	// Writes selected tables (and the joined species view) for taxa in the given clades
+	d := &dumper{columns: make(map[string][]string), logger: logger, opt: opt}
+	if d.opt.Format == "" {
+		d.opt.Format = "csv"
+	}
+	for k, v := range db.Columns {
+		d.columns[k] = strings.Split(v, ",")
+	}
+	tables, err := d.tables()
+	if err != nil {
+		return err
+	}
+	clades, err := parseClades(opt.Clades)
+	if err != nil {
+		return err
+	} else if len(clades) > 0 {
+		d.ids = cladeIDs(corpusTaxa(db), clades)
+		logger.Printf("Found %d taxonomies in %s.\n", len(d.ids), strings.Join(opt.Clades, ", "))
+	}
+	for _, i := range tables {
+		if i == SPECIES {
+			header, rows := speciesView(d.filter(db.GetTable("Taxonomy")), d.filter(db.GetTable("Common")))
+			err = d.write(i, header, rows)
+		} else {
+			err = d.write(i, d.columns[i], d.filter(db.GetTable(i)))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Tests filtered corpus dumps
+
+package taxonomy
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+)
+
+func TestClades(t *testing.T) {
+	taxa := make(map[string]*Taxonomy)
+	for idx, i := range hierSlice() {
+		taxa[strconv.Itoa(idx+1)] = i
+	}
+	taxa["2"].Levels["subfamily"] = "Helodermatinae"
+	cases := []struct {
+		clades []string
+		exp    int
+	}{
+		{[]string{"Class=Reptilia"}, 2},
+		{[]string{"class=aves"}, 1},
+		{[]string{"Orders=Squamata", "Family=Anguidae"}, 1},
+		{[]string{"Subfamily=Helodermatinae"}, 1},
+		{[]string{"Phylum=Chordata"}, 4},
+	}
+	for _, c := range cases {
+		clades, err := parseClades(c.clades)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := len(cladeIDs(taxa, clades)); act != c.exp {
+			t.Errorf("Actual number of taxa %d in %v does not equal expected: %d", act, c.clades, c.exp)
+		}
+	}
+	for _, i := range []string{"Aves", "Clade=Aves"} {
+		if _, err := parseClades([]string{i}); err == nil {
+			t.Errorf("Invalid clade %s did not return an error.", i)
+		}
+	}
+}
+
+func TestSpeciesView(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kestrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	taxa := [][]string{
+		{"1", "Animalia", "Chordata", "Reptilia", "Squamata", "Helodermatidae", "Heloderma", "Heloderma suspectum", "Cope, 1869", "ITIS", "ITIS"},
+		{"2", "Animalia", "Chordata", "Aves", "Passeriformes", "Sturnidae", "Acridotheres", "Acridotheres tristis", "", "ITIS", "ITIS"},
+	}
+	common := [][]string{{"1", "Gila monster", "ITIS"}, {"1", "Beaded lizard", "curated"}, {"2", "Common myna", "ITIS"}}
+	d := &dumper{ids: map[string]bool{"1": true}, logger: kestrelutils.GetLogger(), opt: DumpOptions{Format: "jsonl", Gzip: true, Outdir: dir}}
+	header, rows := speciesView(d.filter(taxa), d.filter(common))
+	if len(rows) != 1 || rows[0][len(rows[0])-1] != "Beaded lizard;Gila monster" {
+		t.Fatalf("Actual species view %v does not equal expected: Heloderma suspectum with two common names", rows)
+	}
+	if err := d.write(SPECIES, header, rows); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path.Join(dir, "Species.jsonl.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var obj map[string]string
+	scanner := bufio.NewScanner(gz)
+	scanner.Scan()
+	if err := json.Unmarshal(scanner.Bytes(), &obj); err != nil {
+		t.Fatal(err)
+	}
+	if obj["ID"] != "1" || obj["Common"] != "Beaded lizard;Gila monster" || obj["Citation"] != "Cope, 1869" {
+		t.Errorf("Actual json record %v does not equal expected.", obj)
+	}
+}
@@ -3,44 +3,66 @@
 package taxonomy
 
 import (
-	"strings"
+	"sort"
 )
 
+type node struct {
+	children map[string]*node
+	level    string
+	name     string
+	parent   *node
+}
+
+func newNode(level, name string, parent *node) *node {
+	// Returns initialized struct
+	n := new(node)
+	n.children = make(map[string]*node)
+	n.level = level
+	n.name = name
+	n.parent = parent
+	return n
+}
+
+func (n *node) lineage() map[string]string {
+	// Returns names of all ancestors by level
+	ret := make(map[string]string)
+	for p := n.parent; p != nil && p.level != ""; p = p.parent {
+		ret[p.level] = p.name
+	}
+	return ret
+}
+
+func conflicts(a, b map[string]string) bool {
+	// Returns true if a and b contain different names for the same level
+	for k, v := range a {
+		if val, ex := b[k]; ex && val != v {
+			return true
+		}
+	}
+	return false
+}
+
 type Hierarchy struct {
-	header   map[string]int
+	homonyms map[string]map[string]bool
+	index    map[string]map[string][]*node
 	levels   []string
-	parents  map[string]string
-	children map[string]string
-	phylum   map[string]string
-	class    map[string]string
-	order    map[string]string
-	family   map[string]string
-	genus    map[string]string
-	species  map[string]string
+	root     *node
 }
 
 func emptyHierarchy() *Hierarchy {
 	// Initializes empty taxonomy hierarchy
 	h := new(Hierarchy)
-	h.levels = []string{"Species", "Genus", "Family", "Order", "Class", "Phylum", "Kingdom"}
-	h.parents = map[string]string{"Phylum": "Kingdom",
-		"Class":   "Phylum",
-		"Order":   "Class",
-		"Family":  "Order",
-		"Genus":   "Family",
-		"Species": "Genus",
+	// Store levels from lowest to highest
+	for idx := len(LEVELS) - 1; idx >= 0; idx-- {
+		h.levels = append(h.levels, LEVELS[idx])
 	}
-	h.children = make(map[string]string)
-	for k, v := range h.parents {
-		// Reverse parents map
-		h.children[v] = k
+	h.homonyms = make(map[string]map[string]bool)
+	h.index = make(map[string]map[string][]*node)
+	for _, i := range h.levels {
+		h.homonyms[i] = make(map[string]bool)
+		h.index[i] = make(map[string][]*node)
 	}
-	h.phylum = make(map[string]string)
-	h.class = make(map[string]string)
-	h.order = make(map[string]string)
-	h.family = make(map[string]string)
-	h.genus = make(map[string]string)
-	h.species = make(map[string]string)
+	h.root = newNode("", "", nil)
 	return h
 }
 
@@ -51,80 +73,131 @@ func NewHierarchy(taxa []*Taxonomy) *Hierarchy {
 	return h
 }
 
-func (h *Hierarchy) FillTaxonomy(t *Taxonomy) {
-	// Replaces NAs with value from hierarchy
-	if strings.ToUpper(t.Genus) == "NA" {
-		t.Genus = h.species[t.Species]
+func (h *Hierarchy) parent(level, name string) (*node, bool) {
+	// Returns nearest known parent of given name if all lineages agree on it
+	var ret *node
+	for _, n := range h.index[level][name] {
+		if n.parent == nil || n.parent.level == "" {
+			continue
+		} else if ret == nil {
+			ret = n.parent
+		} else if ret.level != n.parent.level || ret.name != n.parent.name {
+			return nil, false
+		}
 	}
-	if strings.ToUpper(t.Family) == "NA" {
-		t.Family = h.genus[t.Genus]
+	return ret, ret != nil
+}
+
+func (h *Hierarchy) IsHomonym(level, name string) bool {
+	// Returns true if name occurs in more than one conflicting lineage at level
+	return h.homonyms[level][name]
+}
+
+func (h *Hierarchy) Homonyms() map[string][]string {
+	// Returns sorted homonyms by level
+	ret := make(map[string][]string)
+	for level, names := range h.homonyms {
+		for k := range names {
+			ret[level] = append(ret[level], k)
+		}
+		sort.Strings(ret[level])
 	}
-	if strings.ToUpper(t.Order) == "NA" {
-		t.Order = h.family[t.Family]
+	return ret
+}
+
+func (h *Hierarchy) compatible(t *Taxonomy, level, name string) []map[string]string {
+	// Returns lineages of name which do not conflict with levels already known in t
+	var ret []map[string]string
+	for _, n := range h.index[level][name] {
+		l := n.lineage()
+		var conflict bool
+		for k, v := range l {
+			if val := t.Get(k); !isNA(val) && val != v {
+				conflict = true
+				break
+			}
+		}
+		if !conflict {
+			ret = append(ret, l)
+		}
 	}
-	if strings.ToUpper(t.Class) == "NA" {
-		t.Class = h.order[t.Order]
+	return ret
+}
+
+func agreed(lineages []map[string]string) map[string]string {
+	// Merges lineages if they are compatible, or returns only the names they all share
+	ret := make(map[string]string)
+	homonym := false
+	for idx, i := range lineages {
+		for _, j := range lineages[idx+1:] {
+			if conflicts(i, j) {
+				homonym = true
+			}
+		}
 	}
-	if strings.ToUpper(t.Phylum) == "NA" {
-		t.Phylum = h.class[t.Class]
+	for idx, i := range lineages {
+		for k, v := range i {
+			if !homonym {
+				ret[k] = v
+			} else if idx == 0 {
+				shared := true
+				for _, j := range lineages[1:] {
+					if j[k] != v {
+						shared = false
+						break
+					}
+				}
+				if shared {
+					ret[k] = v
+				}
+			}
+		}
 	}
-	if strings.ToUpper(t.Kingdom) == "NA" {
-		t.Kingdom = h.phylum[t.Phylum]
+	return ret
+}
+
+func (h *Hierarchy) FillTaxonomy(t *Taxonomy) {
+	// Replaces NAs with values from hierarchy unless the lineage is ambiguous
+	for _, level := range h.levels {
+		if name := t.Get(level); !isNA(name) {
+			for k, v := range agreed(h.compatible(t, level, name)) {
+				if isNA(t.Get(k)) {
+					// Higher levels will be examined on later iterations
+					t.set(k, v)
+				}
+			}
+		}
 	}
 	t.CountNAs()
 }
 
-func (h *Hierarchy) setParent(level, parent, child string) {
-	// Stores child as key in level map with parent as key (i.e. stores parent level for each child level)
-	if child != "NA" && parent != "NA" {
-		switch level {
-		case "Phylum":
-			if _, ex := h.phylum[child]; ex == false {
-				h.phylum[child] = parent
-			}
-		case "Class":
-			if _, ex := h.class[child]; ex == false {
-				h.class[child] = parent
-			}
-		case "Order":
-			if _, ex := h.order[child]; ex == false {
-				h.order[child] = parent
-			}
-		case "Family":
-			if _, ex := h.family[child]; ex == false {
-				h.family[child] = parent
-			}
-		case "Genus":
-			if _, ex := h.genus[child]; ex == false {
-				h.genus[child] = parent
-			}
-		case "Species":
-			if _, ex := h.species[child]; ex == false {
-				h.species[child] = parent
-			}
+func (h *Hierarchy) addNode(n *node) {
+	// Indexes new node and records homonyms
+	l := n.lineage()
+	for _, i := range h.index[n.level][n.name] {
+		if conflicts(l, i.lineage()) {
+			h.homonyms[n.level][n.name] = true
+			break
 		}
 	}
+	h.index[n.level][n.name] = append(h.index[n.level][n.name], n)
 }
 
 func (h *Hierarchy) AddTaxonomy(t *Taxonomy) {
-	// Adds individual taxa to hierarchy
-	if _, ex := h.phylum[t.Phylum]; !ex {
-		h.setParent("Phylum", t.Kingdom, t.Phylum)
-	}
-	if _, ex := h.class[t.Class]; !ex {
-		h.setParent("Class", t.Phylum, t.Class)
-	}
-	if _, ex := h.order[t.Order]; !ex {
-		h.setParent("Order", t.Class, t.Order)
-	}
-	if _, ex := h.family[t.Family]; !ex {
-		h.setParent("Family", t.Order, t.Family)
-	}
-	if _, ex := h.genus[t.Genus]; !ex {
-		h.setParent("Genus", t.Family, t.Genus)
-	}
-	if _, ex := h.species[t.Species]; !ex {
-		h.setParent("Species", t.Genus, t.Species)
+	// Adds lineage of individual taxa to hierarchy
+	cur := h.root
+	for idx := len(h.levels) - 1; idx >= 0; idx-- {
+		level := h.levels[idx]
+		if name := t.Get(level); !isNA(name) {
+			key := level + ":" + name
+			child, ex := cur.children[key]
+			if !ex {
+				child = newNode(level, name, cur)
+				cur.children[key] = child
+				h.addNode(child)
+			}
+			cur = child
+		}
 	}
 }
 
@@ -20,30 +20,15 @@ func TestHierarchy(t *testing.T) {
 	taxa := hierSlice()
 	h := NewHierarchy(taxa)
 	for _, i := range taxa {
-		if _, ex := h.species[i.Species]; !ex {
-			t.Errorf("%s not found in species map.", i.Species)
-		} else if v := h.species[i.Species]; v != i.Genus {
-			t.Errorf("%s genus %s is incorrect.", i.Species, v)
-		} else if _, ex := h.genus[i.Genus]; !ex {
-			t.Errorf("%s not found in genus map.", i.Genus)
-		} else if v := h.genus[i.Genus]; v != i.Family {
-			t.Errorf("%s family %s does not equal %s.", i.Genus, v, i.Family)
-		} else if _, ex := h.family[i.Family]; !ex {
-			t.Errorf("%s not found in family map.", i.Family)
-		} else if v := h.family[i.Family]; v != i.Order {
-			t.Errorf("%s order %s does not equal %s.", i.Family, v, i.Order)
-		} else if _, ex := h.order[i.Order]; !ex {
-			t.Errorf("%s not found in order map.", i.Order)
-		} else if v := h.order[i.Order]; v != i.Class {
-			t.Errorf("%s class %s does not equal %s.", i.Order, v, i.Class)
-		} else if _, ex := h.class[i.Class]; !ex {
-			t.Errorf("%s not found in class map.", i.Class)
-		} else if v := h.class[i.Class]; v != i.Phylum {
-			t.Errorf("%s phylum %s does not equal %s.", i.Class, v, i.Phylum)
-		} else if _, ex := h.phylum[i.Phylum]; !ex {
-			t.Errorf("%s not found in phylum map.", i.Phylum)
-		} else if v := h.phylum[i.Phylum]; v != i.Kingdom {
-			t.Errorf("%s kingdom %s does not equal %s.", i.Phylum, v, i.Kingdom)
+		for idx := len(MAJOR) - 1; idx > 0; idx-- {
+			level := MAJOR[idx]
+			if p, ex := h.parent(level, i.Get(level)); !ex {
+				t.Errorf("%s not found in %s map.", i.Get(level), level)
+				break
+			} else if p.level != MAJOR[idx-1] || p.name != i.Get(MAJOR[idx-1]) {
+				t.Errorf("%s %s %s does not equal %s.", i.Get(level), p.level, p.name, i.Get(MAJOR[idx-1]))
+				break
+			}
 		}
 	}
 }
@@ -58,3 +43,53 @@ func TestFillTaxonomy(t *testing.T) {
 		}
 	}
 }
+
+func TestFillLevels(t *testing.T) {
+	taxa := hierSlice()
+	taxa[1].Levels["subspecies"] = "Heloderma suspectum cinctum"
+	taxa[3].Levels["subfamily"] = "Sturninae"
+	h := NewHierarchy(taxa)
+	a := NewTaxonomy()
+	a.Levels["subspecies"] = "Heloderma suspectum cinctum"
+	h.FillTaxonomy(a)
+	if a.Nas != 0 {
+		t.Errorf("%s contains %d NAs.", a.Name(), a.Nas)
+	}
+	a = NewTaxonomy()
+	a.Genus = "Acridotheres"
+	h.FillTaxonomy(a)
+	if v := a.Get("subfamily"); v != "Sturninae" {
+		t.Errorf("Actual subfamily %s does not equal expected: Sturninae", v)
+	} else if a.Kingdom != "Animalia" {
+		t.Errorf("Actual kingdom %s does not equal expected: Animalia", a.Kingdom)
+	}
+}
+
+func TestHomonyms(t *testing.T) {
+	taxa := hierSlice()
+	taxa = append(taxa, testtaxa([]string{"Plantae", "Tracheophyta", "Magnoliopsida", "Rosales", "Moraceae", "Morus", "Morus alba"}, true, 0))
+	taxa = append(taxa, testtaxa([]string{"Animalia", "Chordata", "Aves", "Suliformes", "Sulidae", "Morus", "Morus bassanus"}, true, 0))
+	h := NewHierarchy(taxa)
+	if !h.IsHomonym("genus", "Morus") {
+		t.Error("Morus was not recorded as a homonym.")
+	} else if h.IsHomonym("genus", "Acheta") {
+		t.Error("Acheta was erroneously recorded as a homonym.")
+	}
+	a := NewTaxonomy()
+	a.Genus = "Morus"
+	h.FillTaxonomy(a)
+	if a.Kingdom != "NA" || a.Family != "NA" {
+		t.Errorf("Ambiguous genus was filled with %s and %s.", a.Kingdom, a.Family)
+	}
+	a.Class = "Aves"
+	h.FillTaxonomy(a)
+	if a.Kingdom != "Animalia" || a.Family != "Sulidae" {
+		t.Errorf("Actual lineage %s, %s does not equal expected: Animalia, Sulidae", a.Kingdom, a.Family)
+	}
+	a = NewTaxonomy()
+	a.Species = "Morus alba"
+	h.FillTaxonomy(a)
+	if a.Kingdom != "Plantae" || a.Genus != "Morus" {
+		t.Errorf("Actual lineage %s, %s does not equal expected: Plantae, Morus", a.Kingdom, a.Genus)
+	}
+}
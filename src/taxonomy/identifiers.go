@@ -0,0 +1,79 @@
+// Defines external identifier methods and output configuration
+
+package taxonomy
+
+import (
+	"fmt"
+	"github.com/icwells/go-tools/strarray"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"strings"
+)
+
+func (o *Output) SetIdentifiers(sources string) error {
+	// Stores comma-seperated identifier sources (or all) for output
+	sources = strings.TrimSpace(strings.ToUpper(sources))
+	o.Identifiers = nil
+	if sources == "" {
+		return nil
+	} else if sources == "ALL" {
+		o.Identifiers = kestrelutils.IDENTIFIERS
+		return nil
+	}
+	keep := make(map[string]bool)
+	for _, i := range strings.Split(sources, ",") {
+		i = strings.TrimSpace(i)
+		if !strarray.InSliceStr(kestrelutils.IDENTIFIERS, i) {
+			return fmt.Errorf("%s is not a recognized identifier source", i)
+		}
+		keep[i] = true
+	}
+	for _, i := range kestrelutils.IDENTIFIERS {
+		if keep[i] {
+			o.Identifiers = append(o.Identifiers, i)
+		}
+	}
+	return nil
+}
+
+func (o *Output) IdentifierHeader() []string {
+	// Returns column names for output identifiers
+	var ret []string
+	for _, i := range o.Identifiers {
+		ret = append(ret, kestrelutils.IdentifierColumn(i))
+	}
+	return ret
+}
+
+func (t *Taxonomy) SetIdentifier(source, id string) {
+	// Stores id for given source
+	source = strings.ToUpper(strings.TrimSpace(source))
+	id = strings.TrimSpace(id)
+	if id != "" && !isNA(id) && strarray.InSliceStr(kestrelutils.IDENTIFIERS, source) {
+		if t.Identifiers == nil {
+			t.Identifiers = make(map[string]string)
+		}
+		t.Identifiers[source] = id
+	}
+}
+
+func (t *Taxonomy) MergeIdentifiers(x *Taxonomy) {
+	// Adds identifiers from x which are not present in t
+	for k, v := range x.Identifiers {
+		if _, ex := t.Identifiers[k]; !ex {
+			t.SetIdentifier(k, v)
+		}
+	}
+}
+
+func (t *Taxonomy) IdentifierValues(sources []string) []string {
+	// Returns identifiers for given output sources
+	var ret []string
+	for _, i := range sources {
+		if v, ex := t.Identifiers[i]; ex {
+			ret = append(ret, v)
+		} else {
+			ret = append(ret, "NA")
+		}
+	}
+	return ret
+}
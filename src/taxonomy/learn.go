@@ -0,0 +1,152 @@
+// Adds confirmed and reviewed search results to the taxonomy corpus
+
+package taxonomy
+
+import (
+	"fmt"
+	"github.com/icwells/dbIO"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// CURATED is stored in the DB column of rows which were learned from search results
+var CURATED = "curated"
+
+type learner struct {
+	common    map[string]bool
+	confirmed int
+	ids       map[int]string
+	levels    map[int]string
+	source    int
+	term      int
+	u         *uploader
+}
+
+func newLearner(db *dbIO.DBIO, logger *log.Logger) *learner {
+	// Reads existing taxonomies and common names
+	l := new(learner)
+	l.common = make(map[string]bool)
+	l.ids = make(map[int]string)
+	l.levels = make(map[int]string)
+	l.u = newUploader(db, 1, logger)
+	for k, t := range corpusTaxa(db) {
+		l.u.names[t.Name()] = k
+		l.u.taxa = append(l.u.taxa, t)
+		if id, err := strconv.Atoi(k); err == nil && id >= l.u.tid {
+			l.u.tid = id + 1
+		}
+	}
+	for _, i := range db.GetTable("Common") {
+		l.common[strings.ToLower(i[1])] = true
+	}
+	l.u.hier = NewHierarchy(l.u.taxa)
+	return l
+}
+
+func (l *learner) setColumns(h map[string]int, names []string) error {
+	// Stores indeces of result file columns
+	for _, i := range []string{"Query", "SearchTerm", "Source", "Confirmed"} {
+		if _, ex := h[i]; !ex {
+			return fmt.Errorf("%s column not found in result file", i)
+		}
+	}
+	l.confirmed = h["Confirmed"]
+	l.source = h["Source"]
+	l.term = h["SearchTerm"]
+	// Taxonomy levels are stored between search term and source
+	for idx := l.term + 1; idx < l.source; idx++ {
+		l.levels[idx] = strings.ToLower(names[idx])
+	}
+	for _, i := range kestrelutils.IDENTIFIERS {
+		if idx, ex := h[kestrelutils.IdentifierColumn(i)]; ex {
+			l.ids[idx] = i
+		}
+	}
+	return nil
+}
+
+func (l *learner) taxonomy(row []string) *Taxonomy {
+	// Returns taxonomy stored in result row
+	t := NewTaxonomy()
+	for idx, level := range l.levels {
+		t.set(level, row[idx])
+	}
+	for idx, source := range l.ids {
+		if idx < len(row) && !isNA(row[idx]) {
+			t.SetIdentifier(source, row[idx])
+		}
+	}
+	t.Source = row[l.source]
+	l.u.hier.FillTaxonomy(t)
+	return t
+}
+
+func (l *learner) addRow(row []string) {
+	// Stores new taxonomy and common name from confirmed row
+	t := l.taxonomy(row)
+	name := t.Name()
+	if isNA(t.Species) || isNA(name) {
+		return
+	}
+	id, ex := l.u.names[name]
+	if !ex {
+		id = strconv.Itoa(l.u.tid)
+		l.u.res = append(l.u.res, l.u.taxonomyRow(t, id, CURATED))
+		l.u.storeLevels(t, id)
+		l.u.storeIdentifiers(t, id)
+		l.u.names[name] = id
+		l.u.hier.AddTaxonomy(t)
+		l.u.tid++
+	}
+	// Store formatted search term since it is what is compared to the corpus
+	term := kestrelutils.CorrectSpaces(strings.TrimSpace(row[l.term]))
+	key := strings.ToLower(term)
+	if _, ex := l.u.names[term]; !ex && len(term) > 2 && !l.common[key] && !strings.EqualFold(term, name) {
+		l.u.commontable = append(l.u.commontable, []string{id, term, CURATED, kestrelutils.UNSPECIFIED})
+		l.common[key] = true
+	}
+}
+
+func (l *learner) readResults(infile string) (int, error) {
+	// Reads confirmed rows from result file and returns number of rows examined
+	var count int
+	reader, err := kestrelutils.NewReader(infile, true)
+	if err != nil {
+		return count, err
+	}
+	defer reader.Close()
+	if err = l.setColumns(reader.Header, reader.Names); err != nil {
+		return count, err
+	}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil && !kestrelutils.IsParseError(err) {
+			return count, err
+		} else if err == nil && len(row) > l.confirmed && row[l.confirmed] == "yes" {
+			count++
+			l.addRow(row)
+		}
+	}
+	return count, nil
+}
+
+func Learn(db *dbIO.DBIO, infile string, logger *log.Logger) error {
+	// Inserts new taxa and common names from confirmed results into the corpus
+	if err := kestrelutils.VerifyFile(infile); err != nil {
+		return err
+	}
+	l := newLearner(db, logger)
+	logger.Printf("Reading confirmed results from %s...\n", infile)
+	count, err := l.readResults(infile)
+	if err != nil {
+		return err
+	}
+	logger.Printf("Found %d new taxonomies and %d new common names in %d confirmed results.\n", len(l.u.res), len(l.u.commontable), count)
+	l.u.uploadTables()
+	return nil
+}
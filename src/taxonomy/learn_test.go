@@ -0,0 +1,61 @@
+// Tests learning from confirmed results
+
+package taxonomy
+
+import (
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLearn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kestrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	infile := path.Join(dir, "searchResults.csv")
+	results := `Query,SearchTerm,Kingdom,Phylum,Class,Order,Family,Genus,Species,Source,Confirmed,ITIS_ID
+gray fox,Gray fox,Animalia,Chordata,Mammalia,Carnivora,Canidae,Urocyon,Urocyon cinereoargenteus,NCBI,yes,180599
+GREY FOX,Grey fox,Animalia,Chordata,Mammalia,Carnivora,Canidae,Urocyon,Urocyon cinereoargenteus,NCBI,yes,180599
+coyote,Coyote,Animalia,Chordata,Mammalia,Carnivora,Canidae,Canis,Canis latrans,NCBI,yes,NA
+wolf,Wolf,Animalia,Chordata,Mammalia,Carnivora,Canidae,Canis,Canis lupus,NCBI,no,NA
+Canis lupus,Canis lupus,Animalia,Chordata,Mammalia,Carnivora,Canidae,Canis,Canis lupus,NCBI,yes,NA
+`
+	ioutil.WriteFile(infile, []byte(results), 0644)
+	l := new(learner)
+	l.common = map[string]bool{"coyote": true}
+	l.ids = make(map[int]string)
+	l.levels = make(map[int]string)
+	l.u = newUploader(nil, 1, kestrelutils.GetLogger())
+	l.u.names["Canis latrans"] = "1"
+	l.u.tid = 2
+	if _, err := l.readResults(infile); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.u.res) != 2 {
+		t.Errorf("Actual number of new taxonomies %d does not equal expected: 2", len(l.u.res))
+	}
+	for _, i := range l.u.res {
+		if i[len(i)-1] != CURATED {
+			t.Errorf("Actual DB value %s does not equal expected: %s", i[len(i)-1], CURATED)
+		}
+	}
+	exp := [][]string{{"2", "Gray fox", CURATED, kestrelutils.UNSPECIFIED}, {"2", "Grey fox", CURATED, kestrelutils.UNSPECIFIED}}
+	if len(l.u.commontable) != len(exp) {
+		t.Fatalf("Actual common names %v do not equal expected: %v", l.u.commontable, exp)
+	}
+	for idx, i := range exp {
+		for j := range i {
+			if l.u.commontable[idx][j] != i[j] {
+				t.Errorf("Actual common name row %v does not equal expected: %v", l.u.commontable[idx], i)
+				break
+			}
+		}
+	}
+	if len(l.u.idtable) != 1 || l.u.idtable[0][2] != "180599" {
+		t.Errorf("Actual identifiers %v do not equal expected: 180599", l.u.idtable)
+	}
+}
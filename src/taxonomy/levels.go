@@ -0,0 +1,84 @@
+// Defines ordered taxonomic levels and output configuration
+
+package taxonomy
+
+import (
+	"fmt"
+	"github.com/icwells/go-tools/strarray"
+	"strings"
+)
+
+var (
+	// LEVELS stores every recognized level from highest to lowest
+	LEVELS = []string{"kingdom", "subkingdom", "infrakingdom", "superphylum", "phylum", "subphylum", "infraphylum", "superclass", "class", "subclass", "infraclass", "superorder", "order", "suborder", "infraorder", "superfamily", "family", "subfamily", "tribe", "subtribe", "genus", "subgenus", "species", "subspecies", "variety", "form"}
+	// MAJOR stores the seven levels stored in the Taxonomy table
+	MAJOR = []string{"kingdom", "phylum", "class", "order", "family", "genus", "species"}
+	// INFRASPECIFIC stores levels below species which are formatted as trinomials
+	INFRASPECIFIC = []string{"subspecies", "variety", "form"}
+)
+
+// Output stores levels, identifier sources, and CITES listings written to result files
+type Output struct {
+	CITES       bool
+	Identifiers []string
+	Levels      []string
+	listed      map[string]string
+}
+
+func NewOutput() *Output {
+	// Returns output of the seven major levels without identifiers
+	o := new(Output)
+	o.Levels = MAJOR
+	return o
+}
+
+func isNA(s string) bool {
+	// Returns true if s is empty or NA
+	return s == "" || strings.ToUpper(s) == "NA"
+}
+
+func isMajor(level string) bool {
+	// Returns true if level is stored in a named Taxonomy field
+	return strarray.InSliceStr(MAJOR, level)
+}
+
+func (o *Output) SetLevels(levels string) error {
+	// Adds comma-seperated levels (or all) to the default output levels
+	levels = strings.TrimSpace(strings.ToLower(levels))
+	if levels == "" {
+		o.Levels = MAJOR
+		return nil
+	} else if levels == "all" {
+		o.Levels = LEVELS
+		return nil
+	}
+	keep := make(map[string]bool)
+	for _, i := range MAJOR {
+		keep[i] = true
+	}
+	for _, i := range strings.Split(levels, ",") {
+		i = strings.TrimSpace(i)
+		if !strarray.InSliceStr(LEVELS, i) {
+			return fmt.Errorf("%s is not a recognized taxonomic level", i)
+		}
+		keep[i] = true
+	}
+	// Store in hierarchical order
+	var ret []string
+	for _, i := range LEVELS {
+		if keep[i] {
+			ret = append(ret, i)
+		}
+	}
+	o.Levels = ret
+	return nil
+}
+
+func (o *Output) Header() []string {
+	// Returns title case column names for output levels
+	var ret []string
+	for _, i := range o.Levels {
+		ret = append(ret, strarray.TitleCase(i))
+	}
+	return ret
+}
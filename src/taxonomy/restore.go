@@ -0,0 +1,268 @@
+// Restores corpus tables from dumped csv files
+
+package taxonomy
+
+import (
+	"fmt"
+	"github.com/icwells/dbIO"
+	"github.com/icwells/go-tools/iotools"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"io"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// TABLES stores corpus tables in the order they must be loaded (Taxonomy is referenced by every other table)
+var TABLES = []string{"Taxonomy", "Common", "Levels", "Identifiers", "Cites"}
+
+type restorer struct {
+	columns  map[string][]string
+	ids      map[string]bool
+	logger   *log.Logger
+	present  map[string]bool
+	problems [][]string
+	tables   map[string][][]string
+}
+
+func newRestorer(columns map[string]string, logger *log.Logger) *restorer {
+	// Returns struct with column names by table
+	r := new(restorer)
+	r.columns = make(map[string][]string)
+	for k, v := range columns {
+		r.columns[k] = strings.Split(v, ",")
+	}
+	r.ids = make(map[string]bool)
+	r.logger = logger
+	r.present = make(map[string]bool)
+	r.tables = make(map[string][][]string)
+	return r
+}
+
+func dumpFile(dir, table string) string {
+	// Returns path to dumped table (optionally gzipped) or an empty string if it is not present
+	for _, i := range []string{table + ".csv", table + ".csv.gz"} {
+		if f := path.Join(dir, i); iotools.Exists(f) {
+			return f
+		}
+	}
+	return ""
+}
+
+func (r *restorer) problem(table string, row int, msg string) {
+	// Records row which will not be loaded
+	r.problems = append(r.problems, []string{table, strconv.Itoa(row), msg})
+}
+
+func (r *restorer) check(table string, n int, row []string) bool {
+	// Returns true if row id is valid and unique for Taxonomy or refers to a loaded taxonomy for other tables
+	id := row[0]
+	if _, err := strconv.Atoi(id); err != nil {
+		r.problem(table, n, fmt.Sprintf("ID %s is not an integer", id))
+		return false
+	} else if table == "Taxonomy" {
+		if r.ids[id] {
+			r.problem(table, n, fmt.Sprintf("duplicate ID %s", id))
+			return false
+		}
+		r.ids[id] = true
+	} else if !r.ids[id] {
+		r.problem(table, n, fmt.Sprintf("ID %s does not refer to a restored taxonomy", id))
+		return false
+	}
+	return true
+}
+
+func (r *restorer) readTable(table, infile string) error {
+	// Reads dumped table and stores rows in table column order
+	columns, ex := r.columns[table]
+	if !ex {
+		return fmt.Errorf("%s is not a corpus table", table)
+	}
+	reader, err := kestrelutils.NewReader(infile, true)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if _, ex := reader.Header[columns[0]]; !ex {
+		return fmt.Errorf("%s column not found in %s", columns[0], infile)
+	}
+	for _, i := range columns {
+		if _, ex := reader.Header[i]; !ex {
+			// Columns which were added after the dump was made are left empty
+			r.logger.Printf("%s column not found in %s; it will be left empty.\n", i, infile)
+		}
+	}
+	var n int
+	for {
+		s, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		n++
+		if err != nil {
+			if !kestrelutils.IsParseError(err) {
+				// Stop at unreadable (e.g. truncated) files instead of recording every failed read
+				return fmt.Errorf("cannot read %s at row %d: %v", infile, n, err)
+			}
+			r.problem(table, n, err.Error())
+			continue
+		}
+		var row []string
+		for _, i := range columns {
+			var v string
+			if idx, ex := reader.Header[i]; ex && idx < len(s) {
+				v = s[idx]
+			}
+			row = append(row, v)
+		}
+		if r.check(table, n, row) {
+			r.tables[table] = append(r.tables[table], row)
+		}
+	}
+	return nil
+}
+
+func (r *restorer) readTables(dir string) error {
+	// Reads every dumped corpus table in dir
+	if infile := dumpFile(dir, "Taxonomy"); infile == "" {
+		return fmt.Errorf("Taxonomy.csv not found in %s", dir)
+	}
+	for _, i := range TABLES {
+		if infile := dumpFile(dir, i); infile != "" {
+			r.logger.Printf("Reading %s...\n", infile)
+			r.present[i] = true
+			if err := r.readTable(i, infile); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func restoreKey(e *entry) string {
+	// Returns source and identifier (or lowest name for rows without an identifier from their source) of entry
+	return e.db() + "\t" + e.key(e.db())
+}
+
+func (r *restorer) remap(taxa, levels, ids [][]string) map[string]bool {
+	// Replaces ids of dumped taxonomies with ids of existing taxonomies with the same source key and assigns new ids to dumped taxonomies whose id is taken; returns ids of existing taxonomies
+	all := func(string) bool { return true }
+	if !r.present["Levels"] {
+		levels = nil
+	}
+	if !r.present["Identifiers"] {
+		// Match on names if identifiers were not dumped
+		ids = nil
+	}
+	var tid int
+	keys := make(map[string]string)
+	taken := make(map[string]bool)
+	local, _ := entries(taxa, levels, ids, nil, all)
+	for id, e := range local {
+		keys[restoreKey(e)] = id
+		taken[id] = true
+		if n, err := strconv.Atoi(id); err == nil && n > tid {
+			tid = n
+		}
+	}
+	for id := range r.ids {
+		if n, err := strconv.Atoi(id); err == nil && n > tid {
+			tid = n
+		}
+	}
+	tid++
+	newids := make(map[string]string)
+	existing := make(map[string]bool)
+	dumped, order := entries(r.tables["Taxonomy"], r.tables["Levels"], r.tables["Identifiers"], nil, all)
+	for _, id := range order {
+		if l, ex := keys[restoreKey(dumped[id])]; ex && !existing[l] {
+			newids[id] = l
+			existing[l] = true
+		} else if taken[id] {
+			newids[id] = strconv.Itoa(tid)
+			tid++
+		}
+	}
+	for _, i := range TABLES {
+		for _, row := range r.tables[i] {
+			if id, ex := newids[row[0]]; ex {
+				row[0] = id
+			}
+		}
+	}
+	return existing
+}
+
+func (r *restorer) upsert(db *dbIO.DBIO) error {
+	// Updates existing taxonomies which are present in the dump and replaces their rows in dumped tables
+	existing := r.remap(db.GetTable("Taxonomy"), db.GetTable("Levels"), db.GetTable("Identifiers"))
+	r.logger.Printf("Updating %d existing taxonomies...\n", len(existing))
+	var set []string
+	for _, i := range r.columns["Taxonomy"][1:] {
+		set = append(set, i+" = ?")
+	}
+	cmd := fmt.Sprintf("UPDATE Taxonomy SET %s WHERE ID = ?;", strings.Join(set, ", "))
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	var taxa [][]string
+	for _, row := range r.tables["Taxonomy"] {
+		if !existing[row[0]] {
+			taxa = append(taxa, row)
+			continue
+		}
+		var args []interface{}
+		for _, i := range row[1:] {
+			args = append(args, i)
+		}
+		args = append(args, row[0])
+		if _, err = tx.Exec(cmd, args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	r.tables["Taxonomy"] = taxa
+	for _, table := range TABLES[1:] {
+		if !r.present[table] {
+			// Keep rows of tables which were not dumped
+			continue
+		}
+		for id := range existing {
+			if _, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE ID = ?;", table), id); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func Restore(db *dbIO.DBIO, dir string, update bool, logger *log.Logger) error {
+	// Loads dumped tables from dir into corpus; taxonomies with the same source and identifier or name are updated if update is true
+	r := newRestorer(db.Columns, logger)
+	if err := r.readTables(dir); err != nil {
+		return err
+	}
+	if len(r.problems) > 0 {
+		outfile := "KestrelRestoreErrors.csv"
+		logger.Printf("Skipping %d inconsistent rows (see %s).\n", len(r.problems), outfile)
+		if err := kestrelutils.WriteCSV(outfile, []string{"Table", "Row", "Problem"}, r.problems); err != nil {
+			return err
+		}
+	}
+	if update {
+		if err := r.upsert(db); err != nil {
+			return err
+		}
+	}
+	for _, i := range TABLES {
+		if rows := r.tables[i]; len(rows) > 0 {
+			logger.Printf("Uploading %d rows to %s...\n", len(rows), i)
+			db.UploadSlice(i, rows)
+		}
+	}
+	return nil
+}
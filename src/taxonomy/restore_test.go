@@ -0,0 +1,152 @@
+// Tests restoring dumped tables
+
+package taxonomy
+
+import (
+	"compress/gzip"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func writeDump(t *testing.T) string {
+	// Writes dumped tables to temporary directory
+	dir, err := ioutil.TempDir("", "kestrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taxa := `ID,Kingdom,Phylum,Class,Orders,Family,Genus,Species,Citation,DB
+1,Animalia,Chordata,Reptilia,Squamata,Helodermatidae,Heloderma,Heloderma suspectum,"Cope, 1869",ITIS
+2,Animalia,Chordata,Aves,Passeriformes,Sturnidae,Acridotheres,Acridotheres tristis,,ITIS
+2,Animalia,Chordata,Insecta,Orthoptera,Gryllidae,Acheta,Acheta domesticus,,ITIS
+x,Animalia,Chordata,Mammalia,Carnivora,Canidae,Canis,Canis latrans,,curated
+`
+	ioutil.WriteFile(path.Join(dir, "Taxonomy.csv"), []byte(taxa), 0644)
+	f, _ := os.Create(path.Join(dir, "Common.csv.gz"))
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte("ID,Name,DB\n1,Gila monster,ITIS\n2,Common myna,ITIS\n3,House cricket,ITIS\n"))
+	gz.Close()
+	f.Close()
+	return dir
+}
+
+func TestRestore(t *testing.T) {
+	dir := writeDump(t)
+	defer os.RemoveAll(dir)
+	columns := map[string]string{
+		"Taxonomy": "ID,Kingdom,Phylum,Class,Orders,Family,Genus,Species,Citation,DB,Sources",
+		"Common":   "ID,Name,DB",
+		"Levels":   "ID,Level,Name",
+	}
+	r := newRestorer(columns, kestrelutils.GetLogger())
+	if err := r.readTables(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.tables["Taxonomy"]) != 2 || len(r.tables["Common"]) != 2 {
+		t.Errorf("Actual number of rows %d and %d does not equal expected: 2 and 2", len(r.tables["Taxonomy"]), len(r.tables["Common"]))
+	}
+	for _, i := range r.tables["Taxonomy"] {
+		if len(i) != 11 || i[10] != "" {
+			t.Errorf("Actual row %v does not contain an empty Sources column.", i)
+		}
+	}
+	if r.tables["Taxonomy"][0][8] != "Cope, 1869" {
+		t.Errorf("Actual citation %s does not equal expected: Cope, 1869", r.tables["Taxonomy"][0][8])
+	}
+	exp := []string{"Taxonomy 3", "Taxonomy 4", "Common 3"}
+	if len(r.problems) != len(exp) {
+		t.Fatalf("Actual problems %v do not equal expected: %v", r.problems, exp)
+	}
+	for idx, i := range exp {
+		if act := r.problems[idx][0] + " " + r.problems[idx][1]; act != i {
+			t.Errorf("Actual problem row %s does not equal expected: %s", act, i)
+		}
+	}
+	if err := newRestorer(columns, kestrelutils.GetLogger()).readTables(os.TempDir()); err == nil {
+		t.Error("Missing Taxonomy table did not return an error.")
+	}
+}
+
+func TestRestoreTruncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kestrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	infile := path.Join(dir, "Taxonomy.csv.gz")
+	rows := [][]string{}
+	for i := 1; i <= 20000; i++ {
+		rows = append(rows, []string{strconv.Itoa(i), "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis lupus", "Linnaeus, 1758", "ITIS"})
+	}
+	if err := kestrelutils.WriteCSV(infile, []string{"ID", "Kingdom", "Phylum", "Class", "Orders", "Family", "Genus", "Species", "Citation", "DB"}, rows); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(infile)
+	os.Truncate(infile, info.Size()/2)
+	r := newRestorer(map[string]string{"Taxonomy": "ID,Kingdom,Phylum,Class,Orders,Family,Genus,Species,Citation,DB"}, kestrelutils.GetLogger())
+	done := make(chan error)
+	go func() {
+		done <- r.readTables(dir)
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Truncated Taxonomy table did not return an error.")
+		} else if len(r.problems) > 0 {
+			t.Errorf("Actual number of problems %d does not equal expected: 0", len(r.problems))
+		}
+	case <-time.After(10 * time.Second):
+		t.Error("Restoring truncated Taxonomy table did not finish.")
+	}
+}
+
+func TestRestoreRemap(t *testing.T) {
+	dir := writeDump(t)
+	defer os.RemoveAll(dir)
+	columns := map[string]string{
+		"Taxonomy":    "ID,Kingdom,Phylum,Class,Orders,Family,Genus,Species,Citation,DB,Sources",
+		"Common":      "ID,Name,DB",
+		"Identifiers": "ID,Source,Identifier",
+	}
+	taxa := [][]string{
+		{"1", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis latrans", "", CURATED, CURATED},
+		{"5", "Animalia", "Chordata", "Aves", "Passeriformes", "Sturnidae", "Acridotheres", "Acridotheres tristis", "", "ITIS", "ITIS"},
+	}
+	ids := [][]string{{"5", "ITIS", "562148"}}
+	// Taxonomies are matched by name when identifiers were not dumped
+	r := newRestorer(columns, kestrelutils.GetLogger())
+	if err := r.readTables(dir); err != nil {
+		t.Fatal(err)
+	}
+	existing := r.remap(taxa, nil, ids)
+	if len(existing) != 1 || !existing["5"] {
+		t.Errorf("Actual existing ids %v do not equal expected: [5]", existing)
+	}
+	exp := map[string]string{"Heloderma suspectum": "6", "Acridotheres tristis": "5", "Gila monster": "6", "Common myna": "5"}
+	for _, i := range r.tables["Taxonomy"] {
+		if i[0] != exp[i[7]] {
+			t.Errorf("Actual id %s for %s does not equal expected: %s", i[0], i[7], exp[i[7]])
+		}
+	}
+	for _, i := range r.tables["Common"] {
+		if i[0] != exp[i[1]] {
+			t.Errorf("Actual id %s for %s does not equal expected: %s", i[0], i[1], exp[i[1]])
+		}
+	}
+	// Taxonomies with different identifiers from the same source are not matched
+	ioutil.WriteFile(path.Join(dir, "Identifiers.csv"), []byte("ID,Source,Identifier\n2,ITIS,179637\n"), 0644)
+	r = newRestorer(columns, kestrelutils.GetLogger())
+	if err := r.readTables(dir); err != nil {
+		t.Fatal(err)
+	}
+	if existing = r.remap(taxa, nil, ids); len(existing) != 0 {
+		t.Errorf("Actual existing ids %v do not equal expected: []", existing)
+	}
+	if !r.present["Identifiers"] || r.present["Levels"] {
+		t.Errorf("Actual dumped tables %v do not equal expected: Taxonomy, Common, Identifiers", r.present)
+	}
+}
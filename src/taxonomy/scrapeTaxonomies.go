@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"github.com/PuerkitoBio/goquery"
 	"github.com/icwells/kestrel/src/kestrelutils"
+	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -134,10 +136,14 @@ func (t *Taxonomy) ScrapeEOL(result []byte, url string) {
 	}
 }
 
-func (t *Taxonomy) ScrapeItis(url string) {
+func (t *Taxonomy) ScrapeItis(link string) {
 	// Scrapes taxonomy info from itis
-	t.Source = url
-	page, err := goquery.NewDocument(url)
+	t.Source = link
+	if u, err := url.Parse(link); err == nil {
+		// Store tsn from report url
+		t.SetIdentifier("ITIS", u.Query().Get("search_value"))
+	}
+	page, err := goquery.NewDocument(link)
 	if err == nil {
 		found := 0
 		page.Find("table").EachWithBreak(func(i int, table *goquery.Selection) bool {
@@ -171,6 +177,7 @@ func (t *Taxonomy) ScrapeItis(url string) {
 type iucnstruct struct {
 	// https://mholt.github.io/json-to-go/
 	Result []struct {
+		TaxonID int    `json:"taxonid"`
 		Species string `json:"scientific_name"`
 		Kingdom string `json:"kingdom"`
 		Phylum  string `json:"phylum"`
@@ -196,6 +203,9 @@ func (t *Taxonomy) ScrapeIUCN(result []byte, url string) {
 			t.Family = a.Family
 			t.Genus = a.Genus
 			t.Species = a.Species
+			if a.TaxonID > 0 {
+				t.SetIdentifier("IUCN", strconv.Itoa(a.TaxonID))
+			}
 			t.CheckTaxa()
 		}
 	}
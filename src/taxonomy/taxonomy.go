@@ -11,18 +11,19 @@ import (
 )
 
 type Taxonomy struct {
-	ID      string
-	Kingdom string
-	Phylum  string
-	Class   string
-	Order   string
-	Family  string
-	Genus   string
-	Species string
-	Source  string
-	Found   bool
-	Nas     int
-	levels  []string
+	ID          string
+	Kingdom     string
+	Phylum      string
+	Class       string
+	Order       string
+	Family      string
+	Genus       string
+	Species     string
+	Source      string
+	Found       bool
+	Nas         int
+	Identifiers map[string]string
+	Levels      map[string]string
 }
 
 func NewTaxonomy() *Taxonomy {
@@ -38,7 +39,8 @@ func NewTaxonomy() *Taxonomy {
 	t.Source = ""
 	t.Found = false
 	t.Nas = 7
-	t.levels = []string{"kingdom", "phylum", "class", "order", "family", "genus", "species"}
+	t.Identifiers = make(map[string]string)
+	t.Levels = make(map[string]string)
 	return t
 }
 
@@ -48,7 +50,7 @@ func (t *Taxonomy) Slice(id, db string) []string {
 	if id != "" {
 		ret = append(ret, id)
 	}
-	for _, i := range []string{t.Kingdom, t.Phylum, t.Class, t.Order, t.Family, t.Genus, t.Species, t.Source} {
+	for _, i := range append(t.Values(MAJOR), t.Source) {
 		ret = append(ret, strings.Replace(i, `"`, "", -1))
 	}
 	if db != "" {
@@ -57,6 +59,15 @@ func (t *Taxonomy) Slice(id, db string) []string {
 	return ret
 }
 
+func (t *Taxonomy) Values(levels []string) []string {
+	// Returns values for given levels
+	var ret []string
+	for _, i := range levels {
+		ret = append(ret, t.Get(i))
+	}
+	return ret
+}
+
 func (t *Taxonomy) String() string {
 	// Returns formatted string
 	return strings.Join(t.Slice("", ""), ",")
@@ -74,6 +85,77 @@ func (t *Taxonomy) Copy(x *Taxonomy) {
 	t.Source = x.Source
 	t.Found = x.Found
 	t.Nas = x.Nas
+	t.Identifiers = make(map[string]string)
+	for k, v := range x.Identifiers {
+		t.Identifiers[k] = v
+	}
+	t.Levels = make(map[string]string)
+	for k, v := range x.Levels {
+		t.Levels[k] = v
+	}
+}
+
+func (t *Taxonomy) Get(level string) string {
+	// Returns value for given level
+	switch level {
+	case "kingdom":
+		return t.Kingdom
+	case "phylum":
+		return t.Phylum
+	case "class":
+		return t.Class
+	case "order":
+		return t.Order
+	case "family":
+		return t.Family
+	case "genus":
+		return t.Genus
+	case "species":
+		return t.Species
+	}
+	if v, ex := t.Levels[level]; ex {
+		return v
+	}
+	return "NA"
+}
+
+func (t *Taxonomy) set(level, value string) {
+	// Stores value at given level without formatting
+	switch level {
+	case "kingdom":
+		t.Kingdom = value
+	case "phylum":
+		t.Phylum = value
+	case "class":
+		t.Class = value
+	case "order":
+		t.Order = value
+	case "family":
+		t.Family = value
+	case "genus":
+		t.Genus = value
+	case "species":
+		t.Species = value
+	default:
+		if isNA(value) {
+			delete(t.Levels, level)
+		} else if strarray.InSliceStr(LEVELS, level) {
+			if t.Levels == nil {
+				t.Levels = make(map[string]string)
+			}
+			t.Levels[level] = value
+		}
+	}
+}
+
+func (t *Taxonomy) Name() string {
+	// Returns name of lowest identified level
+	for idx := len(LEVELS) - 1; idx >= 0; idx-- {
+		if v := t.Get(LEVELS[idx]); !isNA(v) {
+			return v
+		}
+	}
+	return "NA"
 }
 
 func (t *Taxonomy) SpeciesCaps(name string) string {
@@ -114,20 +196,65 @@ func (t *Taxonomy) clearids() {
 	if _, err := strconv.Atoi(t.Kingdom); err == nil {
 		t.Kingdom = "NA"
 	}
+	for k, v := range t.Levels {
+		if _, err := strconv.Atoi(v); err == nil {
+			delete(t.Levels, k)
+		}
+	}
 	t.CountNAs()
 }
 
 func (t *Taxonomy) CountNAs() {
 	// Rechecks nas
 	nas := 0
-	for _, i := range []string{t.Kingdom, t.Phylum, t.Class, t.Order, t.Family, t.Genus, t.Species} {
-		if i == "" || strings.ToUpper(i) == "NA" {
+	for _, i := range t.Values(MAJOR) {
+		if isNA(i) {
 			nas++
 		}
 	}
 	t.Nas = nas
 }
 
+func (t *Taxonomy) Truncate(level string) {
+	// Replaces levels below given level with NA and removes identifiers of the original taxon
+	var below bool
+	t.Identifiers = make(map[string]string)
+	for _, l := range LEVELS {
+		if below {
+			t.set(l, "NA")
+		} else if l == level {
+			below = true
+		}
+	}
+	t.CountNAs()
+}
+
+func LowestCommon(taxa ...*Taxonomy) *Taxonomy {
+	// Returns levels shared by every taxonomy down to the first level where any of them disagree
+	ret := NewTaxonomy()
+	if len(taxa) == 0 {
+		return ret
+	}
+	for _, l := range LEVELS {
+		v := taxa[0].Get(l)
+		for _, i := range taxa[1:] {
+			if x := i.Get(l); isNA(v) {
+				v = x
+			} else if !isNA(x) && x != v {
+				ret.CountNAs()
+				ret.Found = !isNA(ret.Kingdom)
+				return ret
+			}
+		}
+		if !isNA(v) {
+			ret.set(l, v)
+		}
+	}
+	ret.CountNAs()
+	ret.Found = !isNA(ret.Kingdom)
+	return ret
+}
+
 func (t *Taxonomy) removePunctuation(s string) string {
 	// Removes punctuation from line
 	var ret strings.Builder
@@ -168,6 +295,16 @@ func (t *Taxonomy) checkLevel(l string, sp bool) string {
 	return t.removePunctuation(l)
 }
 
+func (t *Taxonomy) checkInfraspecific(l string) string {
+	// Returns formatted trinomial
+	s := strings.Fields(l)
+	if (len(s) < 3 || strings.Contains(l, ".")) && !isNA(t.Species) {
+		// Prepend binomial to abbreviated or bare epithets
+		return t.Species + " " + strings.ToLower(t.removePunctuation(s[len(s)-1]))
+	}
+	return t.SpeciesCaps(t.removePunctuation(l))
+}
+
 func (t *Taxonomy) CheckTaxa() {
 	// Checks formatting
 	t.CountNAs()
@@ -185,6 +322,13 @@ func (t *Taxonomy) CheckTaxa() {
 		t.Family = t.checkLevel(t.Family, false)
 		t.Genus = t.checkLevel(t.Genus, false)
 		t.Species = kestrelutils.CorrectSpaces(t.checkLevel(t.Species, true))
+		for k, v := range t.Levels {
+			if strarray.InSliceStr(INFRASPECIFIC, k) {
+				t.Levels[k] = t.checkInfraspecific(v)
+			} else {
+				t.Levels[k] = t.checkLevel(v, false)
+			}
+		}
 	}
 }
 
@@ -194,22 +338,10 @@ func (t *Taxonomy) SetLevel(key, value string) {
 	// Ensure value is at least 3 characters (longer than "NA")
 	if len(value) > 2 && !strings.Contains(value, "[") {
 		key = strings.ToLower(key)
-		switch key {
-		case "kingdom":
-			t.Kingdom = value
-		case "phylum":
-			t.Phylum = value
-		case "class":
-			t.Class = value
-		case "order":
-			t.Order = value
-		case "family":
-			t.Family = value
-		case "genus":
-			t.Genus = value
-		case "species":
-			t.Species = kestrelutils.CorrectSpaces(value)
+		if key == "species" || strarray.InSliceStr(INFRASPECIFIC, key) {
+			value = kestrelutils.CorrectSpaces(value)
 		}
+		t.set(key, value)
 	}
 }
 
@@ -225,6 +357,32 @@ func (t *Taxonomy) latinToEnglish(key string) string {
 		key = "order"
 	case "familia":
 		key = "family"
+	case "subregnum":
+		key = "subkingdom"
+	case "superclassis":
+		key = "superclass"
+	case "subclassis":
+		key = "subclass"
+	case "infraclassis":
+		key = "infraclass"
+	case "superordo":
+		key = "superorder"
+	case "subordo":
+		key = "suborder"
+	case "infraordo":
+		key = "infraorder"
+	case "superfamilia":
+		key = "superfamily"
+	case "subfamilia":
+		key = "subfamily"
+	case "tribus":
+		key = "tribe"
+	case "subtribus":
+		key = "subtribe"
+	case "varietas":
+		key = "variety"
+	case "forma":
+		key = "form"
 	}
 	return key
 }
@@ -235,7 +393,7 @@ func (t *Taxonomy) IsLevel(s string, translate bool) string {
 	if translate {
 		s = t.latinToEnglish(s)
 	}
-	if strarray.InSliceStr(t.levels, s) {
+	if strarray.InSliceStr(LEVELS, s) {
 		return s
 	}
 	return ""
@@ -244,7 +402,7 @@ func (t *Taxonomy) IsLevel(s string, translate bool) string {
 func (t *Taxonomy) ContainsLevel(s string) string {
 	// Returns level if s contains one
 	s = strings.ToLower(s)
-	for _, i := range t.levels {
+	for _, i := range MAJOR {
 		if strings.Contains(s, i) {
 			return i
 		}
@@ -98,3 +98,81 @@ func TestSetLevel(t *testing.T) {
 	}
 	compareTaxonomies(t, expected, a)
 }
+
+func TestOutputLevels(t *testing.T) {
+	a := testtaxa([]string{"Animalia", "Chordata", "Mammalia", "Carnivora", "Felidae", "Panthera", "Panthera tigris"}, true, 0)
+	a.SetLevel("subfamily", "Pantherinae")
+	a.SetLevel("subspecies", "P. t. altaica")
+	a.CheckTaxa()
+	o := NewOutput()
+	if err := o.SetLevels("subfamily,subspecies"); err != nil {
+		t.Error(err)
+	}
+	exp := "Animalia,Chordata,Mammalia,Carnivora,Felidae,Pantherinae,Panthera,Panthera tigris,Panthera tigris altaica"
+	if s := strings.Join(a.Values(o.Levels), ","); s != exp {
+		t.Errorf("Actual taxonomy %s does not equal expected: %s", s, exp)
+	} else if a.Name() != "Panthera tigris altaica" {
+		t.Errorf("Actual name %s does not equal expected: Panthera tigris altaica", a.Name())
+	}
+	if err := o.SetLevels("clade"); err == nil {
+		t.Error("Unrecognized level did not return an error.")
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	a := NewTaxonomy()
+	a.SetIdentifier("itis", "180599")
+	a.SetIdentifier("NCBI", "NA")
+	a.SetIdentifier("wikidata", "Q1")
+	b := NewTaxonomy()
+	b.SetIdentifier("NCBI", "9612")
+	b.SetIdentifier("ITIS", "1")
+	a.MergeIdentifiers(b)
+	o := NewOutput()
+	if err := o.SetIdentifiers("itis,ncbi,eol"); err != nil {
+		t.Error(err)
+	}
+	exp := "180599,9612,NA"
+	if v := strings.Join(a.IdentifierValues(o.Identifiers), ","); v != exp {
+		t.Errorf("Actual identifiers %s do not equal expected: %s", v, exp)
+	} else if len(a.Identifiers) != 2 {
+		t.Errorf("Actual number of identifiers %d does not equal expected: 2", len(a.Identifiers))
+	}
+}
+
+func TestLowestCommon(t *testing.T) {
+	wolf := testtaxa([]string{"Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis lupus"}, true, 0)
+	wolf.Levels["subfamily"] = "Caninae"
+	coyote := testtaxa([]string{"Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis latrans"}, true, 0)
+	lion := testtaxa([]string{"Animalia", "Chordata", "Mammalia", "Carnivora", "Felidae", "Panthera", "Panthera leo"}, true, 0)
+	input := []struct {
+		taxa []*Taxonomy
+		exp  *Taxonomy
+	}{
+		{[]*Taxonomy{wolf, coyote}, testtaxa([]string{"Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "NA"}, true, 1)},
+		{[]*Taxonomy{coyote, lion}, testtaxa([]string{"Animalia", "Chordata", "Mammalia", "Carnivora", "NA", "NA", "NA"}, true, 3)},
+		{[]*Taxonomy{wolf, wolf}, wolf},
+	}
+	for _, i := range input {
+		compareTaxonomies(t, i.exp, LowestCommon(i.taxa...))
+	}
+	if a := LowestCommon(wolf, coyote).Get("subfamily"); a != "Caninae" {
+		t.Errorf("Actual subfamily %s does not equal expected: Caninae", a)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	a := testtaxa([]string{"Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis lupus"}, true, 0)
+	a.Levels["subfamily"] = "Caninae"
+	a.Levels["subspecies"] = "Canis lupus familiaris"
+	a.SetIdentifier("ITIS", "180596")
+	a.Truncate("genus")
+	compareTaxonomies(t, testtaxa([]string{"Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "NA"}, true, 1), a)
+	if v := a.Get("subfamily"); v != "Caninae" {
+		t.Errorf("Actual subfamily %s does not equal expected: Caninae", v)
+	} else if v := a.Get("subspecies"); !isNA(v) {
+		t.Errorf("Actual subspecies %s does not equal expected: NA", v)
+	} else if len(a.Identifiers) != 0 {
+		t.Errorf("Actual number of identifiers %d does not equal expected: 0", len(a.Identifiers))
+	}
+}
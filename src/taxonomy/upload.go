@@ -7,8 +7,9 @@ import (
 	"github.com/icwells/dbIO"
 	"github.com/icwells/kestrel/src/kestrelutils"
 	"log"
+	"os"
 	"path"
-	"strconv"
+	"strings"
 	"sync"
 )
 
@@ -31,16 +32,23 @@ func newRank(id, level, name, parent string) *rank {
 
 type uploader struct {
 	citations   map[string]string
-	common      map[string][]string
+	common      map[string][][]string
 	commontable [][]string
+	conflicts   [][]string
 	count       int
 	db          *dbIO.DBIO
 	dir         string
 	hier        *Hierarchy
 	ids         map[string]*rank
+	idtable     [][]string
+	itis        string
+	leveltable  [][]string
 	logger      *log.Logger
+	merged      map[string]*backbone
 	names       map[string]string
 	ncbi        map[string]string
+	order       []string
+	priority    []string
 	proc        int
 	res         [][]string
 	taxa        []*Taxonomy
@@ -51,19 +59,31 @@ func newUploader(db *dbIO.DBIO, proc int, logger *log.Logger) *uploader {
 	// Returns initialized struct
 	u := new(uploader)
 	u.citations = make(map[string]string)
-	u.common = make(map[string][]string)
+	u.common = make(map[string][][]string)
 	u.db = db
 	u.dir = path.Join(kestrelutils.GetLocation(), "databases")
 	u.hier = emptyHierarchy()
 	u.ids = make(map[string]*rank)
 	u.logger = logger
+	u.merged = make(map[string]*backbone)
 	u.names = make(map[string]string)
+	u.priority = PRIORITY
 	u.proc = proc
 	u.tid = 1
 	u.setNCBIfiles()
 	return u
 }
 
+func (u *uploader) setPriority(priority []string) {
+	// Replaces default source priority with given order
+	if len(priority) > 0 {
+		u.priority = nil
+		for _, i := range priority {
+			u.priority = append(u.priority, strings.TrimSpace(i))
+		}
+	}
+}
+
 func (u *uploader) setNCBIfiles() {
 	// Stores ncbi names
 	u.ncbi = make(map[string]string)
@@ -75,40 +95,48 @@ func (u *uploader) setNCBIfiles() {
 func (u *uploader) clear() {
 	// Empties taxa slice and common map between datasets
 	u.citations = make(map[string]string)
-	u.common = make(map[string][]string)
+	u.common = make(map[string][][]string)
 	u.commontable = nil
 	u.count = 0
 	u.ids = make(map[string]*rank)
+	u.idtable = nil
+	u.leveltable = nil
 	u.res = nil
 	u.taxa = nil
 }
 
+func (u *uploader) taxonomyRow(t *Taxonomy, id string, sources ...string) []string {
+	// Returns row for Taxonomy table; the first source is stored as DB and all contributing sources are stored as Sources
+	row := append([]string{id}, t.Values(MAJOR)...)
+	return append(row, strings.Replace(t.Source, `"`, "", -1), sources[0], strings.Join(sources, ","))
+}
+
+func (u *uploader) storeLevels(t *Taxonomy, id string) {
+	// Stores intermediate and infraspecific levels for Levels table
+	for _, i := range LEVELS {
+		if v, ex := t.Levels[i]; ex && !isNA(v) {
+			u.leveltable = append(u.leveltable, []string{id, i, v})
+		}
+	}
+}
+
+func (u *uploader) storeIdentifiers(t *Taxonomy, id string) {
+	// Stores external identifiers for Identifiers table
+	for _, i := range kestrelutils.IDENTIFIERS {
+		if v, ex := t.Identifiers[i]; ex {
+			u.idtable = append(u.idtable, []string{id, i, v})
+		}
+	}
+}
+
 func (u *uploader) storeTaxonomy(wg *sync.WaitGroup, mut *sync.RWMutex, t *Taxonomy, db string) {
-	// Fills in missing fields and stores passing taxonomy
+	// Fills in missing fields and merges passing taxonomy with other sources (subspecies are stored seperately from their species)
 	defer wg.Done()
 	t.clearids()
 	u.hier.FillTaxonomy(t)
 	if t.Nas == 0 {
 		mut.Lock()
-		// Attempt to get existing id
-		id, ex := u.names[t.Species]
-		if !ex {
-			// Upload unique taxonomies
-			id = strconv.Itoa(u.tid)
-			row := t.Slice(id, db)
-			u.res = append(u.res, row)
-			u.names[t.Species] = id
-			u.tid++
-		}
-		if v, ex := u.common[t.ID]; ex {
-			for _, i := range v {
-				if _, ex := u.names[i]; !ex {
-					// Append common names with new/existing id and store to avoid duplicates
-					u.commontable = append(u.commontable, []string{id, i})
-					u.names[i] = id
-				}
-			}
-		}
+		u.merge(t, db, u.common[t.ID])
 		mut.Unlock()
 	}
 }
@@ -119,6 +147,11 @@ func (u *uploader) fillTaxonomies(db string) {
 	var mut sync.RWMutex
 	var count int
 	u.hier.setHierarchy(u.taxa)
+	var homonyms int
+	for _, v := range u.hier.Homonyms() {
+		homonyms += len(v)
+	}
+	u.logger.Printf("Found %d homonyms which will only be filled from known higher levels.\n", homonyms)
 	u.logger.Println("Formatting taxonomies...")
 	for _, i := range u.taxa {
 		if i.Found {
@@ -135,8 +168,49 @@ func (u *uploader) fillTaxonomies(db string) {
 	wg.Wait()
 }
 
-func UploadDatabases(db *dbIO.DBIO, proc int, logger *log.Logger) {
-	// Formats and uploads taxonomy databases to MySQL
+func (u *uploader) uploadTables() {
+	// Uploads formatted rows to corpus tables
+	for _, i := range []struct {
+		table string
+		rows  [][]string
+	}{{"Taxonomy", u.res}, {"Common", u.commontable}, {"Levels", u.leveltable}, {"Identifiers", u.idtable}} {
+		if len(i.rows) > 0 {
+			u.db.UploadSlice(i.table, i.rows)
+		}
+	}
+}
+
+func (u *uploader) writeConflicts(outfile string) {
+	// Writes species whose lineages differ between backbones
+	u.logger.Printf("Found %d lineage conflicts between sources.\n", len(u.conflicts))
+	if len(u.conflicts) > 0 {
+		if err := kestrelutils.WriteCSV(outfile, []string{"Name", "Level", "Source", "Value", "OtherSource", "OtherValue"}, u.conflicts); err != nil {
+			u.logger.Printf("[Error] Cannot write %s: %v\n", outfile, err)
+		}
+	}
+}
+
+func UploadDatabases(db *dbIO.DBIO, proc int, itis string, cites bool, priority []string, logger *log.Logger) {
+	// Formats, merges by source priority, and uploads taxonomy databases to MySQL; ITIS is read from download files in itis if it is given
+	var listed []*Taxonomy
 	u := newUploader(db, proc, logger)
-	u.loadITIS()
+	u.itis = itis
+	u.setPriority(priority)
+	if err := u.readITIS(); err != nil {
+		u.logger.Printf("[Error] %v\n", err)
+		os.Exit(100)
+	}
+	if cites {
+		var err error
+		if listed, err = u.readChecklist(); err != nil {
+			u.logger.Printf("[Error] Cannot load CITES checklist: %v\n", err)
+		}
+	}
+	u.setRows()
+	u.writeConflicts("KestrelConflicts.csv")
+	u.logger.Printf("Uploading %d taxonomies...\n", len(u.res))
+	u.uploadTables()
+	if table := u.citesTable(listed); len(table) > 0 {
+		u.db.UploadSlice("Cites", table)
+	}
 }
@@ -0,0 +1,97 @@
+// Adds the bundled CITES checklist to the corpus and stores the listing table
+
+package taxonomy
+
+import (
+	"fmt"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"io"
+	"path"
+	"strings"
+)
+
+// CITES is stored in the DB column of rows which were read from the CITES checklist
+var CITES = "CITES"
+
+func citesFile() string {
+	// Returns path to bundled CITES checklist
+	return path.Join(kestrelutils.Getutils(), "citesAnimalia.csv.gz")
+}
+
+func citesTaxonomy(row []string, levels map[int]string, source int) *Taxonomy {
+	// Returns taxonomy from checklist row; trinomials are stored as subspecies
+	t := NewTaxonomy()
+	for idx, level := range levels {
+		if idx < len(row) {
+			t.SetLevel(level, row[idx])
+		}
+	}
+	if s := strings.Fields(t.Species); len(s) > 2 {
+		t.SetLevel("subspecies", t.Species)
+		t.Species = strings.Join(s[:2], " ")
+	}
+	if source < len(row) {
+		t.Source = strings.TrimSpace(row[source])
+	}
+	t.Found = true
+	t.CountNAs()
+	return t
+}
+
+func (u *uploader) readCITES(infile string) ([]*Taxonomy, error) {
+	// Returns checklist taxonomies and stores those which are not already in the corpus
+	var ret []*Taxonomy
+	reader, err := kestrelutils.NewReader(infile, true)
+	if err != nil {
+		return ret, err
+	}
+	defer reader.Close()
+	levels := make(map[int]string)
+	for idx, i := range reader.Names {
+		if l := strings.ToLower(i); isMajor(l) {
+			levels[idx] = l
+		}
+	}
+	source, ex := reader.Header["Source"]
+	if !ex || len(levels) != len(MAJOR) {
+		return ret, fmt.Errorf("%s does not contain seven taxonomic levels and a Source column", infile)
+	}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil && !kestrelutils.IsParseError(err) {
+			return ret, err
+		} else if err == nil {
+			t := citesTaxonomy(row, levels, source)
+			ret = append(ret, t)
+			if _, ex := u.names[t.Name()]; !ex {
+				u.taxa = append(u.taxa, t)
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (u *uploader) citesTable(listed []*Taxonomy) [][]string {
+	// Returns Cites table rows with the corpus id of each listed taxon
+	var ret [][]string
+	for _, t := range listed {
+		if id, ex := u.names[t.Name()]; ex {
+			ret = append(ret, []string{id, t.Name(), t.Source})
+		}
+	}
+	return ret
+}
+
+func (u *uploader) readChecklist() ([]*Taxonomy, error) {
+	// Reads CITES checklist and formats taxa which are not already in the corpus
+	fmt.Println()
+	u.clear()
+	u.logger.Println("Reading CITES checklist...")
+	listed, err := u.readCITES(citesFile())
+	if err == nil {
+		u.fillTaxonomies(CITES)
+	}
+	return listed, err
+}
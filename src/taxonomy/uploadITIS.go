@@ -4,28 +4,75 @@ package taxonomy
 
 import (
 	"fmt"
-	"os"
+	"github.com/icwells/go-tools/strarray"
+	"github.com/icwells/kestrel/src/kestrelutils"
 	"strings"
 )
 
-func (u *uploader) itisKingdoms() {
-	// Returns itis kingdom map
-	for _, i := range u.db.GetTable("kingdoms") {
+// itisSource returns rows of ITIS tables from either the MySQL schema or the download files
+type itisSource interface {
+	// authors returns taxon_author_id and shortauthor
+	authors() ([][]string, error)
+	// kingdoms returns kingdom_id and kingdom_name
+	kingdoms() ([][]string, error)
+	// ranks returns kingdom_id, rank_id, and rank_name
+	ranks() ([][]string, error)
+	// units returns tsn, parent_tsn, kingdom_id, rank_id, complete_name, and taxon_author_id of valid taxa
+	units() ([][]string, error)
+	// vernaculars returns tsn, vernacular_name, and language of common names
+	vernaculars() ([][]string, error)
+}
+
+type itisDB struct {
+	u *uploader
+}
+
+func (s itisDB) authors() ([][]string, error) {
+	// Returns strippedauthor table
+	return s.u.db.GetTable("strippedauthor"), nil
+}
+
+func (s itisDB) kingdoms() ([][]string, error) {
+	// Returns kingdoms table
+	return s.u.db.GetTable("kingdoms"), nil
+}
+
+func (s itisDB) ranks() ([][]string, error) {
+	// Returns taxon unit types
+	return s.u.db.GetColumns("taxon_unit_types", []string{"kingdom_id", "rank_id", "rank_name"}), nil
+}
+
+func (s itisDB) units() ([][]string, error) {
+	// Returns valid taxonomic units
+	return s.u.db.GetRows("taxonomic_units", "name_usage", "valid", "tsn,parent_tsn,kingdom_id,rank_id,complete_name,taxon_author_id"), nil
+}
+
+func (s itisDB) vernaculars() ([][]string, error) {
+	// Returns common names in every language
+	return s.u.db.GetColumns("vernaculars", []string{"tsn", "vernacular_name", "language"}), nil
+}
+
+func (u *uploader) itisKingdoms(src itisSource) error {
+	// Stores itis kingdoms
+	rows, err := src.kingdoms()
+	for _, i := range rows {
 		u.ids[i[0]] = newRank(i[0], "kingdom", i[1], "")
 	}
+	return err
 }
 
-func (u *uploader) itisRanks() map[string]map[string]string {
+func (u *uploader) itisRanks(src itisSource) (map[string]map[string]string, error) {
 	// Returns itis ranks stored by kingdom id
 	ranks := make(map[string]map[string]string)
-	for _, i := range u.db.GetColumns("taxon_unit_types", []string{"kingdom_id", "rank_id", "rank_name"}) {
+	rows, err := src.ranks()
+	for _, i := range rows {
 		// Store ranks by rank id and rank ids by kingdom id
 		if _, ex := ranks[i[0]]; !ex {
 			ranks[i[0]] = make(map[string]string)
 		}
 		ranks[i[0]][i[1]] = strings.ToLower(i[2])
 	}
-	return ranks
+	return ranks, err
 }
 
 func (u *uploader) setLevelIDs() {
@@ -51,11 +98,18 @@ func (u *uploader) setLevelIDs() {
 	}
 }
 
-func (u *uploader) setids() {
+func (u *uploader) setids(src itisSource) error {
 	// Loads itis ids
 	species := "species"
-	ranks := u.itisRanks()
-	for _, i := range u.db.GetRows("taxonomic_units", "name_usage", "valid", "tsn,parent_tsn,kingdom_id,rank_id,complete_name") {
+	ranks, err := u.itisRanks(src)
+	if err != nil {
+		return err
+	}
+	rows, err := src.units()
+	if err != nil {
+		return err
+	}
+	for _, i := range rows {
 		id := i[0]
 		kid := i[2]
 		rid := i[3]
@@ -63,76 +117,81 @@ func (u *uploader) setids() {
 		if k, ex := ranks[kid]; ex {
 			if level, e := k[rid]; e {
 				// Only store if rank can be identified
-				if level == species {
+				if level == species || strarray.InSliceStr(INFRASPECIFIC, level) {
 					if _, ex := u.names[name]; !ex {
-						// Store species, and genus
+						// Store lowest level and parent id in genus field
 						t := NewTaxonomy()
-						t.SetLevel(species, name)
+						t.SetLevel(level, name)
 						t.Genus = i[1]
 						t.ID = id
-						if cit, e := u.citations[id]; e {
+						t.SetIdentifier("ITIS", id)
+						if cit, e := u.citations[i[5]]; e {
 							t.Source = cit
 						}
 						u.taxa = append(u.taxa, t)
 					}
-				} else {
-					u.ids[id] = newRank(id, level, name, i[1])
 				}
+				// Store every rank (species are parents of infraspecific taxa)
+				u.ids[id] = newRank(id, level, name, i[1])
 			}
 		}
 	}
+	return nil
 }
 
-func (u *uploader) setITIScitations() {
-	// Stores strippedauthor table in citations map
-	for _, i := range u.db.GetTable("strippedauthor") {
+func (u *uploader) setITIScitations(src itisSource) error {
+	// Stores strippedauthor table in citations map by author id
+	rows, err := src.authors()
+	for _, i := range rows {
 		u.citations[i[0]] = i[1]
 	}
+	return err
 }
 
 func (u *uploader) setcommon(i []string) {
-	// Stores common names
+	// Stores common names and their languages
 	tsn := i[0]
-	if _, ex := u.names[tsn]; !ex {
-		if _, ex := u.common[tsn]; !ex {
-			u.common[tsn] = []string{}
-		}
+	language := kestrelutils.UNSPECIFIED
+	if len(i) > 2 && strings.TrimSpace(i[2]) != "" {
+		language = strings.TrimSpace(i[2])
 	}
-	u.common[tsn] = append(u.common[tsn], i[1])
+	u.common[tsn] = append(u.common[tsn], []string{i[1], language})
 }
 
-func (u *uploader) getcommon() {
+func (u *uploader) getcommon(src itisSource) error {
 	// Stores common names by tsn
-	table := "vernaculars"
-	column := "language"
-	columns := "tsn,vernacular_name"
-	for _, language := range []string{"English", "unspecified"} {
-		for _, i := range u.db.GetRows(table, column, language, columns) {
-			u.setcommon(i)
+	rows, err := src.vernaculars()
+	for _, i := range rows {
+		u.setcommon(i)
+	}
+	return err
+}
+
+func (u *uploader) formatITIS(src itisSource) error {
+	// Reads ITIS tables from src and formats taxonomies for upload
+	for _, f := range []func(itisSource) error{u.getcommon, u.setITIScitations, u.itisKingdoms, u.setids} {
+		if err := f(src); err != nil {
+			return err
 		}
 	}
+	u.setLevelIDs()
+	u.fillTaxonomies("ITIS")
+	return nil
 }
 
-func (u *uploader) loadITIS() {
-	// Uploads ITIS table and formats data into sql database
+func (u *uploader) readITIS() error {
+	// Reads ITIS taxonomies from download files if a directory was given, or from the ITIS schema otherwise
 	fmt.Println()
+	if u.itis != "" {
+		u.logger.Printf("Reading ITIS taxonomies from %s...\n", u.itis)
+		return u.formatITIS(newITISFiles(u.itis))
+	}
 	u.logger.Println("Reading ITIS taxonomies...")
-	// Close upload connection
-	//itis, err := dbIO.Connect(u.db.Host, "ITIS", u.db.User, u.db.Password)
-	_, err := u.db.DB.Exec("USE ITIS;")
-	if err != nil {
-		u.logger.Printf("[Error] Cannot connect to ITIS database: %v\n", err)
-		os.Exit(100)
+	if _, err := u.db.DB.Exec("USE ITIS;"); err != nil {
+		return fmt.Errorf("cannot connect to ITIS database: %v", err)
 	}
-	u.getcommon()
-	u.setITIScitations()
-	u.itisKingdoms()
-	u.setids()
-	u.setLevelIDs()
-	u.fillTaxonomies("ITIS")
+	err := u.formatITIS(itisDB{u})
 	// Revert to taxonomy database
 	u.db.DB.Exec(fmt.Sprintf("USE %s;", u.db.Database))
-	u.logger.Println("Uploading ITIS data...")
-	u.db.UploadSlice("Taxonomy", u.res)
-	u.db.UploadSlice("Common", u.commontable)
+	return err
 }
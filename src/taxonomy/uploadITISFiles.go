@@ -0,0 +1,102 @@
+// Reads ITIS tables from the pipe-delimited download files
+
+package taxonomy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+type itisFiles struct {
+	dir string
+}
+
+func newITISFiles(dir string) itisFiles {
+	// Returns source for files in dir
+	return itisFiles{dir}
+}
+
+func (s itisFiles) path(table string) (string, error) {
+	// Returns path to table file (with or without an extension) in dir or one of its subdirectories
+	for _, pattern := range []string{table, table + ".*", filepath.Join("*", table), filepath.Join("*", table+".*")} {
+		if matches, _ := filepath.Glob(filepath.Join(s.dir, pattern)); len(matches) > 0 {
+			return matches[0], nil
+		}
+	}
+	return "", fmt.Errorf("%s file not found in %s", table, s.dir)
+}
+
+func toUTF8(line string) string {
+	// Converts latin-1 encoded lines to utf-8
+	if utf8.ValidString(line) {
+		return line
+	}
+	var ret strings.Builder
+	for i := 0; i < len(line); i++ {
+		ret.WriteRune(rune(line[i]))
+	}
+	return ret.String()
+}
+
+func (s itisFiles) read(table string, columns []int, keep func([]string) bool) ([][]string, error) {
+	// Returns given columns of rows from pipe-delimited table file which pass keep
+	var ret [][]string
+	infile, err := s.path(table)
+	if err != nil {
+		return ret, err
+	}
+	f, err := os.Open(infile)
+	if err != nil {
+		return ret, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		row := strings.Split(toUTF8(strings.TrimRight(scanner.Text(), "\r")), "|")
+		if keep != nil && !keep(row) {
+			continue
+		}
+		var r []string
+		for _, i := range columns {
+			if i < len(row) {
+				r = append(r, strings.TrimSpace(row[i]))
+			} else {
+				r = append(r, "")
+			}
+		}
+		ret = append(ret, r)
+	}
+	return ret, scanner.Err()
+}
+
+func (s itisFiles) authors() ([][]string, error) {
+	// Returns strippedauthor table
+	return s.read("strippedauthor", []int{0, 1}, nil)
+}
+
+func (s itisFiles) kingdoms() ([][]string, error) {
+	// Returns kingdoms table
+	return s.read("kingdoms", []int{0, 1}, nil)
+}
+
+func (s itisFiles) ranks() ([][]string, error) {
+	// Returns taxon unit types
+	return s.read("taxon_unit_types", []int{0, 1, 2}, nil)
+}
+
+func (s itisFiles) units() ([][]string, error) {
+	// Returns valid taxonomic units
+	return s.read("taxonomic_units", []int{0, 17, 20, 21, 25, 18}, func(row []string) bool {
+		return len(row) > 25 && row[10] == "valid"
+	})
+}
+
+func (s itisFiles) vernaculars() ([][]string, error) {
+	// Returns common names in every language
+	return s.read("vernaculars", []int{0, 1, 2}, nil)
+}
@@ -0,0 +1,88 @@
+// Tests reading ITIS download files
+
+package taxonomy
+
+import (
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func itisUnit(tsn, name, usage, parent, author, rank string) string {
+	// Returns pipe-delimited taxonomic_units row
+	row := make([]string, 26)
+	row[0] = tsn
+	row[10] = usage
+	row[17] = parent
+	row[18] = author
+	row[20] = "5"
+	row[21] = rank
+	row[25] = name
+	return strings.Join(row, "|")
+}
+
+func writeITISFiles(t *testing.T) string {
+	// Writes test ITIS tables to temporary directory
+	dir, err := ioutil.TempDir("", "kestrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	units := []string{
+		itisUnit("202423", "Animalia", "valid", "0", "0", "10"),
+		itisUnit("158852", "Chordata", "valid", "202423", "0", "30"),
+		itisUnit("173747", "Reptilia", "valid", "158852", "0", "60"),
+		itisUnit("174040", "Squamata", "valid", "173747", "0", "100"),
+		itisUnit("174100", "Helodermatidae", "valid", "174040", "0", "140"),
+		itisUnit("174101", "Heloderma", "valid", "174100", "0", "180"),
+		itisUnit("174102", "Heloderma suspectum", "valid", "174101", "1", "220"),
+		itisUnit("174103", "Heloderma horridum", "invalid", "174101", "0", "220"),
+	}
+	files := map[string]string{
+		"kingdoms":         "5|Animalia|2020-01-01\n",
+		"taxon_unit_types": "5|10|Kingdom|10|10|\n5|30|Phylum|10|10|\n5|60|Class|30|30|\n5|100|Order|60|60|\n5|140|Family|100|100|\n5|180|Genus|140|140|\n5|220|Species|180|180|\n",
+		"taxonomic_units":  strings.Join(units, "\n") + "\n",
+		"strippedauthor":   "1|Cope, 1869\n",
+		"vernaculars":      "174102|Gila monster|English|N|2020-01-01|1\n174102|Monstruo de Gila|Spanish|N|2020-01-01|2\n174102|Gila monst\xe9r|unspecified|N|2020-01-01|3\n",
+	}
+	for k, v := range files {
+		ioutil.WriteFile(path.Join(dir, k), []byte(v), 0644)
+	}
+	return dir
+}
+
+func TestITISFiles(t *testing.T) {
+	dir := writeITISFiles(t)
+	defer os.RemoveAll(dir)
+	u := newUploader(nil, 1, kestrelutils.GetLogger())
+	if err := u.formatITIS(newITISFiles(dir)); err != nil {
+		t.Fatal(err)
+	}
+	u.setRows()
+	if len(u.res) != 1 {
+		t.Fatalf("Actual number of taxonomies %d does not equal expected: 1", len(u.res))
+	}
+	exp := []string{"1", "Animalia", "Chordata", "Reptilia", "Squamata", "Helodermatidae", "Heloderma", "Heloderma suspectum", "Cope, 1869", "ITIS", "ITIS"}
+	for idx, i := range exp {
+		if u.res[0][idx] != i {
+			t.Errorf("Actual value %s does not equal expected: %s", u.res[0][idx], i)
+		}
+	}
+	common := [][]string{{"Gila monster", "English"}, {"Monstruo de Gila", "Spanish"}, {"Gila monstér", "unspecified"}}
+	if len(u.commontable) != len(common) {
+		t.Fatalf("Actual common names %v do not equal expected: %v", u.commontable, common)
+	}
+	for idx, i := range common {
+		if u.commontable[idx][1] != i[0] || u.commontable[idx][3] != i[1] {
+			t.Errorf("Actual common name %v does not equal expected: %v", u.commontable[idx][1:], i)
+		}
+	}
+	if len(u.idtable) != 1 || u.idtable[0][2] != "174102" {
+		t.Errorf("Actual identifiers %v do not equal expected: 174102", u.idtable)
+	}
+	if _, err := newITISFiles(os.TempDir()).path("missing_table"); err == nil {
+		t.Error("Missing table file did not return an error.")
+	}
+}
@@ -0,0 +1,109 @@
+// Merges taxonomies from multiple backbones by source priority
+
+package taxonomy
+
+import (
+	"github.com/icwells/go-tools/strarray"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PRIORITY stores the default order in which backbone lineages are preferred
+var PRIORITY = []string{"ITIS", "CITES"}
+
+type backbone struct {
+	common   [][]string
+	sources  []string
+	taxonomy *Taxonomy
+}
+
+func (u *uploader) rank(source string) int {
+	// Returns priority of source; unlisted sources follow listed sources
+	for idx, i := range u.priority {
+		if strings.EqualFold(i, source) {
+			return idx
+		}
+	}
+	return len(u.priority)
+}
+
+func (u *uploader) fillGaps(name string, t, x *Taxonomy, primary, other string) {
+	// Fills levels missing from t with values from x until their lineages conflict and records each conflict
+	var conflict bool
+	for _, l := range LEVELS {
+		a, b := t.Get(l), x.Get(l)
+		if isNA(b) {
+			continue
+		} else if isNA(a) {
+			if !conflict {
+				t.set(l, b)
+			}
+		} else if a != b {
+			conflict = true
+			u.conflicts = append(u.conflicts, []string{name, strarray.TitleCase(l), primary, a, other, b})
+		}
+	}
+	t.MergeIdentifiers(x)
+	if t.Source == "" {
+		t.Source = x.Source
+	}
+	t.CountNAs()
+}
+
+func (u *uploader) merge(t *Taxonomy, source string, common [][]string) {
+	// Stores t or merges it with an existing taxonomy of the same name from another source
+	name := t.Name()
+	b, ex := u.merged[name]
+	if !ex {
+		b = &backbone{taxonomy: t, sources: []string{source}}
+		u.merged[name] = b
+		u.order = append(u.order, name)
+	} else {
+		primary, other := b.sources[0], source
+		if u.rank(source) < u.rank(primary) {
+			// Replace base lineage with the higher priority source
+			b.taxonomy, t = t, b.taxonomy
+			primary, other = source, primary
+		}
+		u.fillGaps(name, b.taxonomy, t, primary, other)
+		if !strarray.InSliceStr(b.sources, source) {
+			b.sources = append(b.sources, source)
+			sort.SliceStable(b.sources, func(i, j int) bool { return u.rank(b.sources[i]) < u.rank(b.sources[j]) })
+		}
+	}
+	for _, i := range common {
+		// Store name, source, and language
+		b.common = append(b.common, []string{i[0], source, i[1]})
+	}
+}
+
+func (u *uploader) setRows() {
+	// Assigns ids to merged taxonomies and stores table rows
+	ids := make(map[string]string)
+	for _, name := range u.order {
+		b := u.merged[name]
+		id, ex := u.names[name]
+		if !ex {
+			// Upload unique taxonomies
+			id = strconv.Itoa(u.tid)
+			u.res = append(u.res, u.taxonomyRow(b.taxonomy, id, b.sources...))
+			u.storeLevels(b.taxonomy, id)
+			u.storeIdentifiers(b.taxonomy, id)
+			u.names[name] = id
+			u.tid++
+		}
+		ids[name] = id
+	}
+	for _, name := range u.order {
+		for _, i := range u.merged[name].common {
+			if _, ex := u.names[i[0]]; !ex {
+				// Append common names with new/existing id and store to avoid duplicates
+				u.commontable = append(u.commontable, []string{ids[name], i[0], i[1], i[2]})
+				u.names[i[0]] = ids[name]
+			}
+		}
+	}
+	u.merged = make(map[string]*backbone)
+	u.order = nil
+}
@@ -0,0 +1,44 @@
+// Tests merging backbones by source priority
+
+package taxonomy
+
+import (
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"testing"
+)
+
+func TestMergeSources(t *testing.T) {
+	u := newUploader(nil, 1, kestrelutils.GetLogger())
+	cites := testtaxa([]string{"Animalia", "Chordata", "Reptilia", "Squamata", "Helodermatidae", "Heloderma", "Heloderma suspectum"}, true, 0)
+	cites.Levels["suborder"] = "Anguimorpha"
+	cites.Levels["subfamily"] = "Helodermatinae"
+	cites.Source = "Cope, 1869"
+	itis := testtaxa([]string{"Animalia", "Chordata", "Reptilia", "Squamata", "Anguidae", "Heloderma", "Heloderma suspectum"}, true, 0)
+	itis.SetIdentifier("ITIS", "174102")
+	// Lower priority source is loaded first
+	u.merge(cites, CITES, [][]string{{"Gila monster", "English"}})
+	u.merge(itis, "ITIS", [][]string{{"Gila monster", "English"}, {"Escorpión", "Spanish"}})
+	u.setRows()
+	if len(u.res) != 1 {
+		t.Fatalf("Actual number of taxonomies %d does not equal expected: 1", len(u.res))
+	}
+	exp := []string{"1", "Animalia", "Chordata", "Reptilia", "Squamata", "Anguidae", "Heloderma", "Heloderma suspectum", "Cope, 1869", "ITIS", "ITIS,CITES"}
+	for idx, i := range exp {
+		if u.res[0][idx] != i {
+			t.Errorf("Actual value %s does not equal expected: %s", u.res[0][idx], i)
+		}
+	}
+	// Suborder is above the conflicting family, but subfamily is below it
+	if len(u.leveltable) != 1 || u.leveltable[0][2] != "Anguimorpha" {
+		t.Errorf("Actual levels %v do not equal expected: [[1 suborder Anguimorpha]]", u.leveltable)
+	}
+	if len(u.conflicts) != 1 || u.conflicts[0][3] != "Anguidae" || u.conflicts[0][5] != "Helodermatidae" {
+		t.Errorf("Actual conflicts %v do not equal expected: Anguidae vs Helodermatidae", u.conflicts)
+	}
+	if len(u.commontable) != 2 || u.commontable[0][2] != CITES || u.commontable[1][3] != "Spanish" {
+		t.Errorf("Actual common names %v do not equal expected: Gila monster (CITES) and Escorpión (ITIS, Spanish)", u.commontable)
+	}
+	if len(u.idtable) != 1 {
+		t.Errorf("Actual number of identifiers %d does not equal expected: 1", len(u.idtable))
+	}
+}
@@ -0,0 +1,413 @@
+// Upserts taxa from a source into an existing corpus without replacing other sources or curated rows
+
+package taxonomy
+
+import (
+	"github.com/icwells/dbIO"
+	"github.com/icwells/go-tools/strarray"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type entry struct {
+	common map[string]string
+	ids    [][]string
+	levels [][]string
+	row    []string
+}
+
+func newEntry(row []string) *entry {
+	// Returns entry for Taxonomy row without its id
+	e := new(entry)
+	e.common = make(map[string]string)
+	e.row = row
+	return e
+}
+
+func (e *entry) taxonomy() *Taxonomy {
+	// Returns taxonomy with levels, citation, and identifiers of entry
+	t := NewTaxonomy()
+	for idx, level := range MAJOR {
+		t.set(level, e.row[idx])
+	}
+	for _, i := range e.levels {
+		t.set(i[0], i[1])
+	}
+	if len(e.row) > len(MAJOR) {
+		t.Source = e.row[len(MAJOR)]
+	}
+	for _, i := range e.ids {
+		t.SetIdentifier(i[0], i[1])
+	}
+	return t
+}
+
+func (e *entry) name() string {
+	// Returns name of lowest level
+	return e.taxonomy().Name()
+}
+
+func (e *entry) db() string {
+	// Returns primary source of entry
+	if len(e.row) > len(MAJOR)+1 {
+		return e.row[len(MAJOR)+1]
+	}
+	return ""
+}
+
+func (e *entry) sources() []string {
+	// Returns all sources of entry
+	if len(e.row) > len(MAJOR)+2 {
+		return strings.Split(e.row[len(MAJOR)+2], ",")
+	}
+	return []string{e.db()}
+}
+
+func (e *entry) key(source string) string {
+	// Returns source identifier, or lowest name for sources without identifiers
+	for _, i := range e.ids {
+		if i[0] == source {
+			return i[1]
+		}
+	}
+	return e.name()
+}
+
+func joinRows(rows [][]string) string {
+	// Returns rows as a sorted string for comparison
+	var s []string
+	for _, i := range rows {
+		s = append(s, strings.Join(i, "\t"))
+	}
+	sort.Strings(s)
+	return strings.Join(s, "\n")
+}
+
+func (e *entry) equals(x *entry) bool {
+	// Returns true if taxonomy, levels, and identifiers are identical
+	return strings.Join(e.row, "\t") == strings.Join(x.row, "\t") && joinRows(e.levels) == joinRows(x.levels) && joinRows(e.ids) == joinRows(x.ids)
+}
+
+func (e *entry) keepOthers(prev *entry, source string) {
+	// Keeps sources of merged row prev and the citation and levels which other sources filled in prev but are missing from e
+	idx := len(MAJOR) + 2
+	if len(prev.row) <= idx || len(e.row) <= idx || prev.row[idx] == source {
+		return
+	}
+	sources := strings.Split(prev.row[idx], ",")
+	for _, i := range strings.Split(e.row[idx], ",") {
+		if !strarray.InSliceStr(sources, i) {
+			sources = append(sources, i)
+		}
+	}
+	e.row[idx] = strings.Join(sources, ",")
+	for i := range MAJOR {
+		if isNA(e.row[i]) && !isNA(prev.row[i]) {
+			e.row[i] = prev.row[i]
+		}
+	}
+	if e.row[len(MAJOR)] == "" {
+		e.row[len(MAJOR)] = prev.row[len(MAJOR)]
+	}
+	levels := make(map[string]bool)
+	for _, i := range e.levels {
+		levels[i[0]] = true
+	}
+	for _, i := range prev.levels {
+		if !levels[i[0]] {
+			e.levels = append(e.levels, i)
+		}
+	}
+}
+
+func entries(taxa, levels, ids, common [][]string, keep func(string) bool) (map[string]*entry, []string) {
+	// Returns entries from table rows with a kept DB value by id and ids in table order (DB follows Citation in Taxonomy rows)
+	var order []string
+	ret := make(map[string]*entry)
+	for _, i := range taxa {
+		if len(i) > len(MAJOR)+2 && keep(i[len(MAJOR)+2]) {
+			ret[i[0]] = newEntry(i[1:])
+			order = append(order, i[0])
+		}
+	}
+	for _, i := range levels {
+		if e, ex := ret[i[0]]; ex {
+			e.levels = append(e.levels, i[1:])
+		}
+	}
+	for _, i := range ids {
+		if e, ex := ret[i[0]]; ex {
+			e.ids = append(e.ids, i[1:])
+		}
+	}
+	for _, i := range common {
+		if e, ex := ret[i[0]]; ex && len(i) > 2 && keep(i[2]) {
+			// Store language by name
+			language := kestrelutils.UNSPECIFIED
+			if len(i) > 3 && i[3] != "" {
+				language = i[3]
+			}
+			e.common[i[1]] = language
+		}
+	}
+	return ret, order
+}
+
+type updater struct {
+	changed   map[string]*entry
+	existing  map[string]string
+	old       map[string]*entry
+	others    map[string]string
+	outranked map[string]*entry
+	removed   []string
+	report    [][]string
+	source    string
+	u         *uploader
+}
+
+func newUpdater(u *uploader, source string) *updater {
+	// Returns struct with existing corpus rows
+	up := new(updater)
+	up.changed = make(map[string]*entry)
+	up.existing = make(map[string]string)
+	up.others = make(map[string]string)
+	up.outranked = make(map[string]*entry)
+	up.source = source
+	up.u = u
+	return up
+}
+
+func (up *updater) setExisting(taxa, levels, ids, common [][]string) {
+	// Stores existing rows by source key and sets the next id after every existing row
+	for _, i := range taxa {
+		if id, err := strconv.Atoi(i[0]); err == nil && id >= up.u.tid {
+			up.u.tid = id + 1
+		}
+	}
+	var order []string
+	up.old, order = entries(taxa, levels, ids, common, func(db string) bool { return db == up.source })
+	for _, id := range order {
+		up.existing[up.old[id].key(up.source)] = id
+	}
+	others, _ := entries(taxa, levels, ids, nil, func(db string) bool { return db != up.source })
+	for id, e := range others {
+		up.others[e.name()] = id
+		if up.u.rank(e.db()) > up.u.rank(up.source) {
+			// Rows from lower priority sources are merged into source taxa with the same name
+			up.outranked[id] = e
+		}
+	}
+}
+
+func (up *updater) seedNames() {
+	// Stores names of rows from higher priority sources so source taxa with the same name are not inserted a second time
+	for k, v := range up.others {
+		if _, ex := up.outranked[v]; !ex {
+			up.u.names[k] = v
+		}
+	}
+}
+
+func (up *updater) readCorpus() {
+	// Reads existing rows from the corpus
+	db := up.u.db
+	up.setExisting(db.GetTable("Taxonomy"), db.GetTable("Levels"), db.GetTable("Identifiers"), db.GetTable("Common"))
+}
+
+func (up *updater) record(action, id string, e *entry) {
+	// Stores row for update report
+	up.report = append(up.report, []string{up.source, action, id, e.name()})
+}
+
+func (up *updater) outrank(e, prev *entry) *entry {
+	// Returns e with levels missing from the source filled from lower priority row prev and the sources of both
+	t := e.taxonomy()
+	up.u.fillGaps(t.Name(), t, prev.taxonomy(), up.source, prev.db())
+	sources := []string{up.source}
+	for _, i := range prev.sources() {
+		if !strarray.InSliceStr(sources, i) {
+			sources = append(sources, i)
+		}
+	}
+	sort.SliceStable(sources, func(i, j int) bool { return up.u.rank(sources[i]) < up.u.rank(sources[j]) })
+	ret := newEntry(append(t.Values(MAJOR), strings.Replace(t.Source, `"`, "", -1), up.source, strings.Join(sources, ",")))
+	for _, i := range LEVELS {
+		if v, ex := t.Levels[i]; ex && !isNA(v) {
+			ret.levels = append(ret.levels, []string{i, v})
+		}
+	}
+	// Identifiers of other sources are kept in place
+	ret.ids = e.ids
+	ret.common = e.common
+	return ret
+}
+
+func (up *updater) diff() {
+	// Replaces formatted source rows with rows which are new to the corpus and stores changes and removals
+	u := up.u
+	current, order := entries(u.res, u.leveltable, u.idtable, u.commontable, func(db string) bool { return db == up.source })
+	u.res, u.leveltable, u.idtable, u.commontable = nil, nil, nil, nil
+	seen := make(map[string]bool)
+	for _, id := range order {
+		e := current[id]
+		if old, ex := up.existing[e.key(up.source)]; ex && !seen[old] {
+			seen[old] = true
+			prev := up.old[old]
+			e.keepOthers(prev, up.source)
+			if !prev.equals(e) {
+				up.changed[old] = e
+				up.record("changed", old, e)
+				// Levels and identifiers of changed rows are replaced after the old rows are deleted
+				for _, i := range e.levels {
+					u.leveltable = append(u.leveltable, append([]string{old}, i...))
+				}
+				for _, i := range e.ids {
+					u.idtable = append(u.idtable, append([]string{old}, i...))
+				}
+			}
+			for c, l := range e.common {
+				if _, ex := prev.common[c]; !ex {
+					u.commontable = append(u.commontable, []string{old, c, up.source, l})
+				}
+			}
+			u.names[e.name()] = old
+		} else if prev, ex := up.outranked[up.others[e.name()]]; ex {
+			// Replace lineage of lower priority row and keep its contributions
+			other := up.others[e.name()]
+			delete(up.outranked, other)
+			m := up.outrank(e, prev)
+			up.changed[other] = m
+			up.record("changed", other, m)
+			for _, i := range m.levels {
+				u.leveltable = append(u.leveltable, append([]string{other}, i...))
+			}
+			for _, i := range m.ids {
+				u.idtable = append(u.idtable, append([]string{other}, i...))
+			}
+			for c, l := range m.common {
+				u.commontable = append(u.commontable, []string{other, c, up.source, l})
+			}
+			u.names[e.name()] = other
+		} else {
+			up.record("added", id, e)
+			u.res = append(u.res, append([]string{id}, e.row...))
+			for _, i := range e.levels {
+				u.leveltable = append(u.leveltable, append([]string{id}, i...))
+			}
+			for _, i := range e.ids {
+				u.idtable = append(u.idtable, append([]string{id}, i...))
+			}
+			for c, l := range e.common {
+				u.commontable = append(u.commontable, []string{id, c, up.source, l})
+			}
+		}
+	}
+	for id, e := range up.old {
+		if !seen[id] {
+			up.removed = append(up.removed, id)
+			up.record("removed", id, e)
+		}
+	}
+	sort.Strings(up.removed)
+}
+
+func (up *updater) apply() error {
+	// Updates changed rows, deletes removed rows, and uploads new rows
+	tx, err := up.u.db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	for id, e := range up.changed {
+		stmts := []struct {
+			cmd  string
+			args []interface{}
+		}{
+			{"UPDATE Taxonomy SET Kingdom = ?, Phylum = ?, Class = ?, Orders = ?, Family = ?, Genus = ?, Species = ?, Citation = ?, DB = ?, Sources = ? WHERE ID = ?;", nil},
+			{"DELETE FROM Levels WHERE ID = ?;", []interface{}{id}},
+			{"DELETE FROM Identifiers WHERE ID = ? AND Source = ?;", []interface{}{id, up.source}},
+		}
+		for _, i := range e.row[:len(MAJOR)+1] {
+			stmts[0].args = append(stmts[0].args, i)
+		}
+		sources := up.source
+		if len(e.row) > len(MAJOR)+2 {
+			sources = e.row[len(MAJOR)+2]
+		}
+		stmts[0].args = append(stmts[0].args, up.source, sources, id)
+		for _, i := range stmts {
+			if _, err = tx.Exec(i.cmd, i.args...); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	for _, id := range up.removed {
+		// Common names, levels, and identifiers are removed by cascade
+		if _, err = tx.Exec("DELETE FROM Taxonomy WHERE ID = ?;", id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	up.u.uploadTables()
+	return nil
+}
+
+func (up *updater) update(read func() error) error {
+	// Reads existing corpus and source rows and applies the difference
+	up.readCorpus()
+	if err := read(); err != nil {
+		return err
+	}
+	up.u.setRows()
+	up.diff()
+	up.u.logger.Printf("Found %d new, %d changed, and %d removed %s taxa.\n", len(up.u.res), len(up.changed), len(up.removed), up.source)
+	return up.apply()
+}
+
+func UpdateDatabases(db *dbIO.DBIO, proc int, itis string, cites bool, priority []string, outfile string, logger *log.Logger) error {
+	// Upserts ITIS (and optionally CITES) taxa into existing corpus by source priority and writes a report of each change
+	var report [][]string
+	u := newUploader(db, proc, logger)
+	u.itis = itis
+	u.setPriority(priority)
+	up := newUpdater(u, "ITIS")
+	err := up.update(func() error {
+		// Taxa from higher priority sources are not duplicated by new ITIS taxa
+		up.seedNames()
+		return u.readITIS()
+	})
+	report = append(report, up.report...)
+	if err == nil && cites {
+		var listed []*Taxonomy
+		u.clear()
+		u.names = make(map[string]string)
+		up = newUpdater(u, CITES)
+		err = up.update(func() error {
+			// Only taxa which are missing from higher priority sources are added from the checklist
+			up.seedNames()
+			listed, err = u.readChecklist()
+			return err
+		})
+		if err == nil {
+			if _, err = db.DB.Exec("DELETE FROM Cites;"); err == nil {
+				db.UploadSlice("Cites", u.citesTable(listed))
+			}
+		}
+		report = append(report, up.report...)
+	}
+	u.writeConflicts("KestrelConflicts.csv")
+	if outfile == "" {
+		outfile = "KestrelUpdates.csv"
+	}
+	logger.Printf("Writing %d changes to %s...\n", len(report), outfile)
+	if e := kestrelutils.WriteCSV(outfile, []string{"Source", "Action", "ID", "Name"}, report); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,143 @@
+// Tests incremental corpus updates
+
+package taxonomy
+
+import (
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"testing"
+)
+
+func TestUpdateDiff(t *testing.T) {
+	u := newUploader(nil, 1, kestrelutils.GetLogger())
+	up := newUpdater(u, "ITIS")
+	taxa := [][]string{
+		{"1", "Animalia", "Chordata", "Reptilia", "Squamata", "Helodermatidae", "Heloderma", "Heloderma suspectum", "Cope 1869", "ITIS"},
+		{"2", "Animalia", "Chordata", "Reptilia", "Squamata", "Anguidae", "Abronia", "Abronia graminea", "", "ITIS"},
+		{"3", "Animalia", "Arthropoda", "Insecta", "Orthoptera", "Gryllidae", "Acheta", "Acheta domesticus", "", "ITIS"},
+		{"4", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis latrans", "", CURATED},
+		{"5", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Vulpes", "Vulpes vulpes", "Linnaeus, 1758", "ITIS", "ITIS,CITES"},
+	}
+	levels := [][]string{{"5", "tribe", "Vulpini"}}
+	ids := [][]string{{"1", "ITIS", "100"}, {"2", "ITIS", "200"}, {"3", "ITIS", "300"}, {"5", "ITIS", "500"}}
+	common := [][]string{{"4", "Coyote", CURATED}}
+	up.setExisting(taxa, levels, ids, common)
+	if u.tid != 6 {
+		t.Errorf("Actual next id %d does not equal expected: 6", u.tid)
+	}
+	u.res = [][]string{
+		{"5", "Animalia", "Chordata", "Reptilia", "Squamata", "Helodermatidae", "Heloderma", "Heloderma suspectum", "Cope 1869", "ITIS"},
+		{"6", "Animalia", "Chordata", "Reptilia", "Squamata", "Xenosauridae", "Abronia", "Abronia graminea", "", "ITIS"},
+		{"7", "Animalia", "Chordata", "Aves", "Passeriformes", "Sturnidae", "Acridotheres", "Acridotheres tristis", "", "ITIS"},
+		{"8", "Animalia", "Chordata", "Mammalia", "Carnivora", "NA", "Vulpes", "Vulpes vulpes", "", "ITIS", "ITIS"},
+	}
+	u.idtable = [][]string{{"5", "ITIS", "100"}, {"6", "ITIS", "200"}, {"7", "ITIS", "400"}, {"8", "ITIS", "500"}}
+	u.commontable = [][]string{{"5", "Gila monster", "ITIS", "English"}, {"7", "Common myna", "ITIS", "English"}, {"7", "Miná común", "ITIS", "Spanish"}}
+	up.diff()
+	if len(u.res) != 1 || u.res[0][0] != "7" {
+		t.Errorf("Actual new rows %v do not equal expected: Acridotheres tristis", u.res)
+	}
+	// Rows merged with other sources should keep their contributions
+	if _, ex := up.changed["2"]; !ex || len(up.changed) != 1 {
+		t.Errorf("Actual number of changed rows %d does not equal expected: 1", len(up.changed))
+	}
+	if len(up.removed) != 1 || up.removed[0] != "3" {
+		t.Errorf("Actual removed rows %v do not equal expected: [3]", up.removed)
+	}
+	exp := map[string]string{"Gila monster": "1", "Common myna": "7", "Miná común": "7"}
+	if len(u.commontable) != len(exp) {
+		t.Errorf("Actual common names %v do not equal expected: %v", u.commontable, exp)
+	}
+	for _, i := range u.commontable {
+		if exp[i[1]] != i[0] {
+			t.Errorf("Actual id %s for %s does not equal expected: %s", i[0], i[1], exp[i[1]])
+		} else if i[1] == "Miná común" && i[3] != "Spanish" {
+			t.Errorf("Actual language %s for %s does not equal expected: Spanish", i[3], i[1])
+		}
+	}
+	if len(u.idtable) != 2 || u.idtable[0][0] != "2" {
+		t.Errorf("Actual identifiers %v do not equal expected: [[2 ITIS 200] [7 ITIS 400]]", u.idtable)
+	}
+	if len(up.report) != 3 {
+		t.Errorf("Actual number of reported changes %d does not equal expected: 3", len(up.report))
+	}
+	if up.others["Canis latrans"] != "4" {
+		t.Errorf("Actual curated id %s does not equal expected: 4", up.others["Canis latrans"])
+	}
+}
+
+func TestUpdateNames(t *testing.T) {
+	u := newUploader(nil, 1, kestrelutils.GetLogger())
+	// Curated rows are preferred when listed ahead of the source
+	u.setPriority([]string{CURATED, "ITIS"})
+	up := newUpdater(u, "ITIS")
+	taxa := [][]string{
+		{"1", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis latrans", "", CURATED},
+	}
+	up.setExisting(taxa, nil, nil, nil)
+	up.seedNames()
+	for _, i := range [][]string{{"Canidae", "Canis", "Canis latrans"}, {"Canidae", "Vulpes", "Vulpes vulpes"}} {
+		x := NewTaxonomy()
+		x.Kingdom, x.Phylum, x.Class, x.Order, x.Family, x.Genus, x.Species = "Animalia", "Chordata", "Mammalia", "Carnivora", i[0], i[1], i[2]
+		u.merge(x, "ITIS", nil)
+	}
+	u.setRows()
+	if len(u.res) != 1 || u.res[0][7] != "Vulpes vulpes" {
+		t.Errorf("Actual new rows %v do not equal expected: Vulpes vulpes", u.res)
+	}
+	if u.names["Canis latrans"] != "1" {
+		t.Errorf("Actual id %s for Canis latrans does not equal expected: 1", u.names["Canis latrans"])
+	}
+}
+
+func TestUpdatePriority(t *testing.T) {
+	u := newUploader(nil, 1, kestrelutils.GetLogger())
+	up := newUpdater(u, "ITIS")
+	taxa := [][]string{
+		{"1", "Animalia", "Chordata", "Mammalia", "Carnivora", "NA", "Canis", "Canis latrans", "", CURATED, CURATED},
+		{"2", "Animalia", "Chordata", "Aves", "Passeriformes", "Sturnidae", "Acridotheres", "Acridotheres tristis", "Linnaeus, 1766", CITES, CITES},
+	}
+	levels := [][]string{{"1", "tribe", "Canini"}}
+	ids := [][]string{{"2", CITES, "2000"}}
+	up.setExisting(taxa, levels, ids, nil)
+	up.seedNames()
+	if len(u.names) != 0 {
+		t.Errorf("Actual seeded names %v do not equal expected: []", u.names)
+	}
+	u.res = [][]string{
+		{"3", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis latrans", "Say, 1823", "ITIS", "ITIS"},
+		{"4", "Animalia", "Chordata", "Aves", "Passeriformes", "Sturnidae", "Acridotheres", "Acridotheres tristis", "", "ITIS", "ITIS"},
+	}
+	u.idtable = [][]string{{"3", "ITIS", "180599"}, {"4", "ITIS", "562148"}}
+	u.commontable = [][]string{{"3", "Coyote", "ITIS", "English"}}
+	up.diff()
+	if len(u.res) != 0 {
+		t.Errorf("Actual new rows %v do not equal expected: []", u.res)
+	}
+	if len(up.changed) != 2 || len(up.report) != 2 || up.report[0][1] != "changed" {
+		t.Errorf("Actual changes %v do not equal expected: [1 2]", up.report)
+	}
+	if e, ex := up.changed["1"]; !ex {
+		t.Error("Curated row was not merged into ITIS taxonomy.")
+	} else {
+		exp := []string{"Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis latrans", "Say, 1823", "ITIS", "ITIS," + CURATED}
+		for idx, i := range exp {
+			if e.row[idx] != i {
+				t.Errorf("Actual merged value %s does not equal expected: %s", e.row[idx], i)
+			}
+		}
+		if len(e.levels) != 1 || e.levels[0][1] != "Canini" {
+			t.Errorf("Actual merged levels %v do not equal expected: [[tribe Canini]]", e.levels)
+		}
+	}
+	if e, ex := up.changed["2"]; ex && e.row[len(MAJOR)] != "Linnaeus, 1766" {
+		t.Errorf("Actual citation %s does not equal expected: Linnaeus, 1766", e.row[len(MAJOR)])
+	}
+	for _, i := range u.idtable {
+		if i[1] != "ITIS" || (i[0] != "1" && i[0] != "2") {
+			t.Errorf("Actual identifier %v was not stored with merged row.", i)
+		}
+	}
+	if len(u.commontable) != 1 || u.commontable[0][0] != "1" {
+		t.Errorf("Actual common names %v do not equal expected: [[1 Coyote ITIS English]]", u.commontable)
+	}
+}
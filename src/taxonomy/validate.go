@@ -0,0 +1,218 @@
+// Checks result taxonomies against the corpus hierarchy and internal consistency rules
+
+package taxonomy
+
+import (
+	"fmt"
+	"github.com/icwells/dbIO"
+	"github.com/icwells/go-tools/strarray"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"io"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Violation struct {
+	Basis     string
+	Found     string
+	Level     string
+	Rule      string
+	Suggested string
+}
+
+func (v Violation) in(s []Violation) bool {
+	// Returns true if s already suggests the same fix for the same level
+	for _, i := range s {
+		if i.Level == v.Level && i.Suggested == v.Suggested {
+			return true
+		}
+	}
+	return false
+}
+
+func corpusTaxa(db *dbIO.DBIO) map[string]*Taxonomy {
+	// Returns taxonomies from Taxonomy and Levels tables by id
+	ret := make(map[string]*Taxonomy)
+	levels := make(map[string][][]string)
+	for _, i := range db.GetTable("Levels") {
+		levels[i[0]] = append(levels[i[0]], i[1:])
+	}
+	for _, i := range db.GetTable("Taxonomy") {
+		t := NewTaxonomy()
+		for idx, level := range MAJOR {
+			t.set(level, i[idx+1])
+		}
+		for _, v := range levels[i[0]] {
+			t.set(v[0], v[1])
+		}
+		t.CountNAs()
+		ret[i[0]] = t
+	}
+	return ret
+}
+
+func (t *Taxonomy) consistency() []Violation {
+	// Returns violations of binomial and trinomial naming within t
+	var ret []Violation
+	if !isNA(t.Species) && !isNA(t.Genus) {
+		if genus := strings.Fields(t.Species)[0]; genus != t.Genus {
+			ret = append(ret, Violation{t.Species, t.Genus, "genus", "binomial genus differs from genus", genus})
+		}
+	}
+	for _, i := range INFRASPECIFIC {
+		if v := t.Get(i); !isNA(v) && !isNA(t.Species) && !strings.HasPrefix(v, t.Species+" ") {
+			suggested := "NA"
+			if f := strings.Fields(v); len(f) > 2 {
+				suggested = strings.Join(f[:2], " ")
+			}
+			ret = append(ret, Violation{v, t.Species, "species", fmt.Sprintf("%s is not within species", i), suggested})
+		}
+	}
+	return ret
+}
+
+func (h *Hierarchy) lineageViolations(t *Taxonomy, level, name string) []Violation {
+	// Returns levels of t which differ from the backbone lineage of name
+	var ret []Violation
+	var lineages []map[string]string
+	for _, n := range h.index[level][name] {
+		lineages = append(lineages, n.lineage())
+	}
+	basis := fmt.Sprintf("%s %s", level, name)
+	for _, l := range LEVELS {
+		if v, ex := agreed(lineages)[l]; ex && !isNA(t.Get(l)) && t.Get(l) != v {
+			ret = append(ret, Violation{basis, t.Get(l), l, "lineage differs from backbone", v})
+		}
+	}
+	if len(ret) == 0 {
+		// Each homonym conflicts with t, but at levels where they disagree with each other
+		ret = append(ret, Violation{basis, name, level, "lineage matches no backbone homonym", "NA"})
+	}
+	return ret
+}
+
+func (h *Hierarchy) Validate(t *Taxonomy) []Violation {
+	// Returns consistency violations and levels which conflict with the backbone lineage of the lowest known name
+	ret := t.consistency()
+	for _, level := range h.levels {
+		if name := t.Get(level); !isNA(name) && len(h.index[level][name]) > 0 {
+			if len(h.compatible(t, level, name)) == 0 {
+				for _, i := range h.lineageViolations(t, level, name) {
+					if !i.in(ret) {
+						ret = append(ret, i)
+					}
+				}
+			}
+			// The lineage of the lowest name in the backbone includes all higher levels
+			break
+		}
+	}
+	return ret
+}
+
+type validator struct {
+	hier    *Hierarchy
+	levels  map[int]string
+	query   int
+	results [][]string
+}
+
+func newValidator(hier *Hierarchy) *validator {
+	// Returns initialized struct
+	v := new(validator)
+	v.hier = hier
+	v.levels = make(map[int]string)
+	v.query = -1
+	return v
+}
+
+func (v *validator) setColumns(names []string) error {
+	// Stores indeces of taxonomic level columns in result or merged files
+	for idx, i := range names {
+		l := strings.ToLower(i)
+		if l == "scientificname" {
+			l = "species"
+		}
+		if strarray.InSliceStr(LEVELS, l) {
+			v.levels[idx] = l
+		} else if i == "Query" && v.query < 0 {
+			v.query = idx
+		}
+	}
+	if len(v.levels) == 0 {
+		return fmt.Errorf("no taxonomic level columns found")
+	}
+	return nil
+}
+
+func (v *validator) checkRow(n int, row []string) {
+	// Stores violations for given row
+	t := NewTaxonomy()
+	for idx, l := range v.levels {
+		if idx < len(row) {
+			t.set(l, row[idx])
+		}
+	}
+	name := t.Name()
+	if v.query >= 0 && v.query < len(row) {
+		name = row[v.query]
+	}
+	for _, i := range v.hier.Validate(t) {
+		v.results = append(v.results, []string{strconv.Itoa(n), name, strarray.TitleCase(i.Level), i.Found, i.Suggested, i.Rule, i.Basis})
+	}
+}
+
+func (v *validator) readFile(infile string) (int, error) {
+	// Checks each row of infile and returns the number of rows
+	var n int
+	reader, err := kestrelutils.NewReader(infile, true)
+	if err != nil {
+		return n, err
+	}
+	defer reader.Close()
+	if err = v.setColumns(reader.Names); err != nil {
+		return n, err
+	}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil && !kestrelutils.IsParseError(err) {
+			return n, err
+		} else if err == nil {
+			n++
+			v.checkRow(n, row)
+		}
+	}
+	return n, nil
+}
+
+func ValidateResults(db *dbIO.DBIO, infile, outfile string, logger *log.Logger) error {
+	// Checks result or merged file against corpus hierarchy and writes violations to outfile
+	if err := kestrelutils.VerifyFile(infile); err != nil {
+		return err
+	}
+	if outfile == "" {
+		dir, _ := path.Split(infile)
+		outfile = path.Join(dir, "KestrelViolations.csv")
+	}
+	var taxa []*Taxonomy
+	for _, t := range corpusTaxa(db) {
+		taxa = append(taxa, t)
+	}
+	v := newValidator(NewHierarchy(taxa))
+	n, err := v.readFile(infile)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(v.results, func(i, j int) bool {
+		a, _ := strconv.Atoi(v.results[i][0])
+		b, _ := strconv.Atoi(v.results[j][0])
+		return a < b
+	})
+	logger.Printf("Found %d violations in %d rows.\n", len(v.results), n)
+	return kestrelutils.WriteCSV(outfile, []string{"Row", "Name", "Level", "Found", "Suggested", "Rule", "Basis"}, v.results)
+}
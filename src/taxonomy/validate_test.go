@@ -0,0 +1,63 @@
+// Tests result validation against the hierarchy
+
+package taxonomy
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	h := NewHierarchy(hierSlice())
+	cases := []struct {
+		levels []string
+		exp    map[string]string
+	}{
+		{[]string{"Animalia", "Chordata", "Reptilia", "Squamata", "Helodermatidae", "Heloderma", "Heloderma suspectum"}, map[string]string{}},
+		{[]string{"Animalia", "Chordata", "Aves", "Squamata", "Helodermatidae", "Heloderma", "Heloderma suspectum"}, map[string]string{"class": "Reptilia"}},
+		{[]string{"Animalia", "Chordata", "Reptilia", "Squamata", "Helodermatidae", "Abronia", "Heloderma suspectum"}, map[string]string{"genus": "Heloderma"}},
+		{[]string{"Animalia", "Chordata", "Mammalia", "Passeriformes", "Sturnidae", "Sturnus", "Sturnus vulgaris"}, map[string]string{"class": "Aves"}},
+	}
+	for _, c := range cases {
+		v := h.Validate(testtaxa(c.levels, true, 0))
+		if len(v) != len(c.exp) {
+			t.Errorf("Actual number of violations %d for %s does not equal expected: %d", len(v), c.levels[6], len(c.exp))
+			continue
+		}
+		for _, i := range v {
+			if c.exp[i.Level] != i.Suggested {
+				t.Errorf("Actual suggestion %s for %s does not equal expected: %s", i.Suggested, i.Level, c.exp[i.Level])
+			}
+		}
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kestrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	infile := path.Join(dir, "merged.csv")
+	rows := `ID,Query,Kingdom,Phylum,Class,Order,Family,Genus,ScientificName
+1,gila monster,Animalia,Chordata,Reptilia,Squamata,Helodermatidae,Heloderma,Heloderma suspectum
+2,cricket,Animalia,Chordata,Insecta,Passeriformes,Gryllidae,Acheta,Acheta domesticus
+`
+	ioutil.WriteFile(infile, []byte(rows), 0644)
+	v := newValidator(NewHierarchy(hierSlice()))
+	n, err := v.readFile(infile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Actual number of rows %d does not equal expected: 2", n)
+	}
+	if len(v.results) != 1 {
+		t.Fatalf("Actual number of violations %d does not equal expected: 1", len(v.results))
+	}
+	if r := v.results[0]; r[0] != "2" || r[1] != "cricket" || r[3] != "Passeriformes" || r[4] != "Orthoptera" {
+		t.Errorf("Actual violation %v does not equal expected.", r)
+	}
+}
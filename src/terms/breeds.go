@@ -0,0 +1,133 @@
+// Defines dictionary of domestic breeds and trade names which resolve to their species
+
+package terms
+
+import (
+	"fmt"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"io"
+	"strings"
+)
+
+var (
+	// BREED is the annotation column for matched breeds
+	BREED = "Breed"
+	// CROSSWORDS may accompany breed names without preventing a match
+	CROSSWORDS = map[string]bool{"": true, "x": true, "mix": true, "mixed": true, "cross": true, "crossbred": true, "crossbreed": true, "hybrid": true, "and": true, "breed": true}
+)
+
+type breed struct {
+	breed   string
+	species string
+}
+
+type breeds struct {
+	longest int
+	names   map[string]breed
+}
+
+func newBreeds() *breeds {
+	// Returns empty breed dictionary
+	b := new(breeds)
+	b.names = make(map[string]breed)
+	return b
+}
+
+func (b *breeds) add(species, name, alias string) {
+	// Stores breed (or generic species name if name is empty) by lower case alias
+	alias = strings.ToLower(strings.Join(strings.Fields(alias), " "))
+	if alias != "" {
+		b.names[alias] = breed{name, species}
+		if n := strings.Count(alias, " ") + 1; n > b.longest {
+			b.longest = n
+		}
+	}
+}
+
+func (o *Options) LoadBreeds(infile string) error {
+	// Replaces breed dictionary with Species, Breed, and semicolon-seperated Aliases columns of infile
+	reader, err := kestrelutils.NewReader(infile, true)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for _, i := range []string{"Species", "Breed"} {
+		if _, ex := reader.Header[i]; !ex {
+			return fmt.Errorf("%s column not found in %s", i, infile)
+		}
+	}
+	s, n := reader.Header["Species"], reader.Header["Breed"]
+	a, aliases := reader.Header["Aliases"]
+	b := newBreeds()
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil && !kestrelutils.IsParseError(err) {
+			return err
+		} else if err != nil || len(row) <= s || len(row) <= n || strings.TrimSpace(row[s]) == "" {
+			continue
+		}
+		species, name := strings.TrimSpace(row[s]), strings.TrimSpace(row[n])
+		b.add(species, name, name)
+		if aliases && a < len(row) {
+			for _, i := range strings.Split(row[a], ";") {
+				b.add(species, name, i)
+			}
+		}
+	}
+	o.breeds = b
+	return nil
+}
+
+func (b *breeds) match(words []string, idx int) (breed, int) {
+	// Returns breed of the longest name starting at idx and its number of words
+	for n := b.longest; n > 0; n-- {
+		if idx+n <= len(words) {
+			if v, ex := b.names[strings.Join(words[idx:idx+n], " ")]; ex {
+				return v, n
+			}
+		}
+	}
+	return breed{}, 0
+}
+
+func (t *Term) checkBreed(o *Options) bool {
+	// Replaces term with domestic species if it names one or more breeds of that species (and optionally a cross) and stores breeds as annotations
+	if len(o.breeds.names) == 0 {
+		return false
+	}
+	var found []string
+	var species string
+	words := strings.Fields(strings.Replace(t.Term, "/", " ", -1))
+	for idx := range words {
+		words[idx] = trimWord(words[idx])
+	}
+	for idx := 0; idx < len(words); {
+		if v, n := o.breeds.match(words, idx); n > 0 {
+			if species != "" && v.species != species {
+				// Crosses between species are handled as hybrids
+				return false
+			}
+			species = v.species
+			if v.breed != "" {
+				found = append(found, v.breed)
+			}
+			idx += n
+		} else if CROSSWORDS[words[idx]] {
+			idx++
+		} else {
+			return false
+		}
+	}
+	if len(found) == 0 {
+		// Generic names are searched as common names
+		return false
+	}
+	t.Term = species
+	t.Scientific = true
+	for _, i := range found {
+		t.annotate(t.Queries[0], BREED, i)
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+// Tests breed dictionary matching
+
+package terms
+
+import (
+	"testing"
+)
+
+// BREEDFILE is the bundled breed dictionary
+var BREEDFILE = "../../utils/breeds.csv"
+
+func TestCheckBreed(t *testing.T) {
+	o := NewOptions()
+	if err := o.LoadBreeds(BREEDFILE); err != nil {
+		t.Fatal(err)
+	}
+	input := []struct {
+		query   string
+		species string
+		breed   string
+	}{
+		{"Pomeranian", "Canis familiaris", "Pomeranian"},
+		{"corgi x", "Canis familiaris", "Corgi"},
+		{"Labrador/Poodle mix", "Canis familiaris", "Labrador retriever;Poodle"},
+		{"DSH", "Felis catus", "Domestic shorthair"},
+		{"holstein cow", "Bos taurus", "Holstein"},
+		{"Yorkshire terrier", "Canis familiaris", "Yorkshire terrier"},
+		{"Burmese python", "", ""},
+		{"dog", "", ""},
+		{"Jersey x Brahman", "", ""},
+	}
+	for _, i := range input {
+		a := NewTerm(i.query)
+		a.Term = i.query
+		if ok := a.checkBreed(o); ok != (i.species != "") {
+			t.Errorf("Actual match %v for %s does not equal expected: %v", ok, i.query, !ok)
+		} else if ok {
+			if a.Term != i.species {
+				t.Errorf("Actual species %s for %s does not equal expected: %s", a.Term, i.query, i.species)
+			}
+			h := o.AnnotationHeader()
+			if h[len(h)-1] != BREED {
+				t.Errorf("Actual header %v does not end with %s.", h, BREED)
+			}
+			if v := a.AnnotationValues(o, i.query); v[len(v)-1] != i.breed {
+				t.Errorf("Actual breed %s for %s does not equal expected: %s", v[len(v)-1], i.query, i.breed)
+			}
+		} else if a.Term != i.query {
+			t.Errorf("Actual term %s for unmatched %s was changed.", a.Term, i.query)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Defines descriptor vocabulary which is removed from search terms and written as annotations
+
+package terms
+
+import (
+	"fmt"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"io"
+	"strings"
+)
+
+type vocabulary struct {
+	categories []string
+	longest    int
+	words      map[string]string
+}
+
+func newVocabulary(words map[string][]string) *vocabulary {
+	// Returns vocabulary of given words by category
+	v := new(vocabulary)
+	v.words = make(map[string]string)
+	for k, val := range words {
+		for _, i := range val {
+			v.add(k, i)
+		}
+	}
+	return v
+}
+
+func (v *vocabulary) add(category, descriptor string) {
+	// Stores lower case descriptor by category
+	descriptor = strings.ToLower(strings.Join(strings.Fields(descriptor), " "))
+	if descriptor == "" {
+		return
+	}
+	var ex bool
+	for _, i := range v.categories {
+		if i == category {
+			ex = true
+			break
+		}
+	}
+	if !ex {
+		v.categories = append(v.categories, category)
+	}
+	v.words[descriptor] = category
+	if n := strings.Count(descriptor, " ") + 1; n > v.longest {
+		v.longest = n
+	}
+}
+
+func (o *Options) LoadDescriptors(infile string) error {
+	// Replaces descriptor vocabulary with Category and Descriptor columns of infile
+	reader, err := kestrelutils.NewReader(infile, true)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for _, i := range []string{"Category", "Descriptor"} {
+		if _, ex := reader.Header[i]; !ex {
+			return fmt.Errorf("%s column not found in %s", i, infile)
+		}
+	}
+	c, d := reader.Header["Category"], reader.Header["Descriptor"]
+	v := newVocabulary(nil)
+	for {
+		s, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil && !kestrelutils.IsParseError(err) {
+			return err
+		} else if err == nil && len(s) > c && len(s) > d {
+			if category := strings.TrimSpace(s[c]); category != "" {
+				v.add(category, s[d])
+			}
+		}
+	}
+	o.descriptors = v
+	return nil
+}
+
+func (o *Options) AnnotationHeader() []string {
+	// Returns descriptor categories (and breed if breeds were loaded) for output header
+	ret := o.descriptors.categories[:len(o.descriptors.categories):len(o.descriptors.categories)]
+	if len(o.breeds.names) > 0 {
+		ret = append(ret, BREED)
+	}
+	return ret
+}
+
+func trimWord(word string) string {
+	// Returns lower case word without surrounding punctuation
+	return strings.ToLower(strings.Trim(word, ",;:.()[]\"'"))
+}
+
+func (v *vocabulary) match(words []string, idx int) (string, string, int) {
+	// Returns category and descriptor of the longest phrase starting at idx and its number of words
+	for n := v.longest; n > 0; n-- {
+		if idx+n <= len(words) {
+			var s []string
+			for _, i := range words[idx : idx+n] {
+				s = append(s, trimWord(i))
+			}
+			phrase := strings.Join(s, " ")
+			if category, ex := v.words[phrase]; ex {
+				return category, phrase, n
+			}
+		}
+	}
+	return "", "", 0
+}
+
+func (t *Term) annotate(query, category, value string) {
+	// Stores annotation for query by category
+	if t.Annotations == nil {
+		t.Annotations = make(map[string]map[string][]string)
+	}
+	if _, ex := t.Annotations[query]; !ex {
+		t.Annotations[query] = make(map[string][]string)
+	}
+	for _, i := range t.Annotations[query][category] {
+		if i == value {
+			return
+		}
+	}
+	t.Annotations[query][category] = append(t.Annotations[query][category], value)
+}
+
+func (t *Term) mergeAnnotations(x *Term) {
+	// Copies annotations of x's queries to t
+	for query, v := range x.Annotations {
+		for category, values := range v {
+			for _, i := range values {
+				t.annotate(query, category, i)
+			}
+		}
+	}
+}
+
+func (t *Term) AnnotationValues(o *Options, query string) []string {
+	// Returns annotations of query in header order of options
+	var ret []string
+	for _, i := range o.AnnotationHeader() {
+		ret = append(ret, strings.Join(t.Annotations[query][i], ";"))
+	}
+	return ret
+}
+
+func (t *Term) annotationMap(query string) map[string]string {
+	// Returns non-empty annotations of query by category
+	var ret map[string]string
+	for k, v := range t.Annotations[query] {
+		if len(v) > 0 {
+			if ret == nil {
+				ret = make(map[string]string)
+			}
+			ret[k] = strings.Join(v, ";")
+		}
+	}
+	return ret
+}
+
+func (t *Term) removeDescriptors(o *Options) {
+	// Removes descriptors from multi-word terms and stores them as annotations of the query
+	words := strings.Fields(t.Term)
+	if len(words) < 2 {
+		return
+	}
+	var kept []string
+	found := make(map[string][]string)
+	var order []string
+	for idx := 0; idx < len(words); {
+		if category, phrase, n := o.descriptors.match(words, idx); n > 0 {
+			if _, ex := found[category]; !ex {
+				order = append(order, category)
+			}
+			found[category] = append(found[category], phrase)
+			idx += n
+		} else {
+			kept = append(kept, words[idx])
+			idx++
+		}
+	}
+	if len(kept) > 0 && len(kept) < len(words) {
+		// Keep terms which consist only of descriptors
+		t.Term = strings.Join(kept, " ")
+		for _, c := range order {
+			for _, i := range found[c] {
+				t.annotate(t.Queries[0], c, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Tests descriptor removal and annotations
+
+package terms
+
+import (
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"github.com/icwells/kestrel/src/taxonomy"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestRemoveDescriptors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kestrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	infile := path.Join(dir, "descriptors.csv")
+	rows := [][]string{{"Age", "adult"}, {"Age", "Juvenile"}, {"Sex", "male"}, {"Sex", "female"}, {"Morph", "albino"}, {"Origin", "captive bred"}, {"Origin", "captive"}}
+	if err := kestrelutils.WriteCSV(infile, []string{"Category", "Descriptor"}, rows); err != nil {
+		t.Fatal(err)
+	}
+	o := NewOptions()
+	if err := o.LoadDescriptors(infile); err != nil {
+		t.Fatal(err)
+	}
+	if a := strings.Join(o.AnnotationHeader(), ","); a != "Age,Sex,Morph,Origin" {
+		t.Errorf("Actual header %s does not equal expected: Age,Sex,Morph,Origin", a)
+	}
+	input := []struct {
+		query  string
+		term   string
+		values string
+	}{
+		{"Captive bred male albino Burmese python", "Burmese python", ",male,albino,captive bred"},
+		{"juvenile female (captive) Coyote", "Coyote", "juvenile,female,,captive"},
+		{"Maleo", "Maleo", ",,,"},
+		{"Female adult", "Female adult", ",,,"},
+		{"Malerba fish", "Malerba fish", ",,,"},
+	}
+	for _, i := range input {
+		a := NewTerm(i.query)
+		a.Term = i.query
+		a.removeDescriptors(o)
+		if a.Term != i.term {
+			t.Errorf("Actual term %s does not equal expected: %s", a.Term, i.term)
+		}
+		if v := strings.Join(a.AnnotationValues(o, i.query), ","); v != i.values {
+			t.Errorf("Actual annotations %s for %s do not equal expected: %s", v, i.query, i.values)
+		}
+	}
+	// Annotations are kept by query when terms are merged
+	a, b := NewTerm("male coyote"), NewTerm("female coyote")
+	a.Term, b.Term = a.Queries[0], b.Queries[0]
+	a.removeDescriptors(o)
+	b.removeDescriptors(o)
+	a.mergeAnnotations(b)
+	if v := a.Record("female coyote", false, taxonomy.NewOutput()).Annotations["Sex"]; v != "female" {
+		t.Errorf("Actual merged annotation %s does not equal expected: female", v)
+	}
+}
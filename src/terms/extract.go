@@ -6,6 +6,7 @@ import (
 	"github.com/icwells/go-tools/iotools"
 	"github.com/icwells/kestrel/src/kestrelutils"
 	"github.com/trustmaster/go-aspell"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -39,23 +40,24 @@ func containsWithSpace(l, target string) bool {
 }
 
 type extractor struct {
-	col     int
-	dir     string
-	fail    [][]string
-	infile  string
-	logger  *log.Logger
-	merged  map[string]*Term
-	min     float64
-	misses  string
-	names   []*Term
-	outfile string
-	script  string
-	speller aspell.Speller
-}
-
-func newExtractor(infile, outfile string, col int, logger *log.Logger) *extractor {
+	col      string
+	dicts    []string
+	dir      string
+	infile   string
+	logger   *log.Logger
+	merged   map[string]*Term
+	min      float64
+	misses   string
+	names    []*Term
+	options  *Options
+	outfile  string
+	rejected []*Term
+	script   string
+	spellers []aspell.Speller
+}
+
+func newExtractor(infile, outfile, col string, options *Options, logger *log.Logger) *extractor {
 	// Returns initialized struct
-	kestrelutils.CheckFile(infile)
 	e := new(extractor)
 	e.col = col
 	e.dir = path.Join(iotools.GetGOPATH(), "src/github.com/icwells/kestrel/nlp/")
@@ -63,33 +65,94 @@ func newExtractor(infile, outfile string, col int, logger *log.Logger) *extracto
 	e.logger = logger
 	e.merged = make(map[string]*Term)
 	e.min = 0.98
+	e.options = options
 	e.outfile = outfile
 	e.script = "namePredictor.py"
-	e.speller, _ = aspell.NewSpeller(map[string]string{"lang": "en_US"})
+	e.setSpellers()
 	dir, _ := path.Split(e.outfile)
 	e.misses = path.Join(dir, "KestrelRejected.csv")
 	return e
 }
 
+func (e *extractor) setSpellers() {
+	// Loads aspell dictionaries for search languages
+	for _, i := range kestrelutils.Dictionaries(e.options.Languages) {
+		if s, err := aspell.NewSpeller(map[string]string{"lang": i}); err != nil {
+			e.logger.Printf("[Error] Cannot load %s spelling dictionary: %v\n", i, err)
+		} else {
+			e.dicts = append(e.dicts, i)
+			e.spellers = append(e.spellers, s)
+		}
+	}
+}
+
+func (e *extractor) vernacular(t *Term) bool {
+	// Returns true if every word of t is found in a dictionary other than English
+	for idx, s := range e.spellers {
+		if e.dicts[idx] != kestrelutils.DICTIONARIES["English"] {
+			known := true
+			for _, w := range strings.Fields(t.Term) {
+				if !s.Check(strings.ToLower(w)) {
+					known = false
+					break
+				}
+			}
+			if known {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *extractor) model() string {
+	// Returns classifier model for the first search language with a trained model
+	for _, i := range e.options.Languages {
+		if m := "nlpModel-" + strings.Replace(i, " ", "", -1); iotools.Exists(path.Join(e.dir, m)) {
+			return m
+		}
+	}
+	return "nlpModel"
+}
+
+func (e *extractor) classifiable() map[string][]*Term {
+	// Returns terms to classify by name; parents are classified in place of hybrid formulas
+	ret := make(map[string][]*Term)
+	for k, v := range e.merged {
+		if v.Hybrid {
+			for _, p := range v.Parents {
+				ret[p.Term] = append(ret[p.Term], p)
+			}
+		} else {
+			ret[k] = append(ret[k], v)
+		}
+	}
+	return ret
+}
+
 func (e *extractor) writeTerms(outfile string) {
 	// Writes input file for name classifier
 	out := iotools.CreateFile(outfile)
 	defer out.Close()
-	for k := range e.merged {
+	for k := range e.classifiable() {
 		out.WriteString(k + "\n")
 	}
 }
 
 func (e *extractor) getClassifications(infile string) {
 	// Reads name classifications from file
+	names := e.classifiable()
 	reader, _ := iotools.YieldFile(infile, false)
 	for i := range reader {
-		if v, ex := e.merged[i[0]]; ex {
+		for _, v := range names[i[0]] {
 			if val, err := strconv.ParseFloat(i[1], 64); err == nil {
-				if val >= e.min {
+				if val >= e.min && !e.vernacular(v) {
+					// Common names in other languages may resemble scientific names to the classifier
 					v.Scientific = true
-					if s := strings.Split(i[0], " "); len(s) > 2 {
-						v.Term = strings.Join(s[:2], " ")
+					v.scientificName()
+					if s := strings.Split(i[0], " "); len(s) > v.nameLength() {
+						// Drop trailing words which were not parsed as epithets
+						v.Term = strings.Join(s[:v.nameLength()], " ")
 					}
 				}
 			}
@@ -106,7 +169,7 @@ func (e *extractor) classifyTerms() {
 	defer os.Chdir(orig)
 	e.writeTerms(infile)
 	defer os.Remove(infile)
-	cmd := exec.Command("python", e.script, infile, outfile)
+	cmd := exec.Command("python", e.script, "-m", e.model(), infile, outfile)
 	if err := cmd.Run(); err != nil {
 		e.logger.Printf("Name classifier failed. %v\n", err)
 	} else {
@@ -114,18 +177,75 @@ func (e *extractor) classifyTerms() {
 		e.getClassifications(outfile)
 	}
 	for _, v := range e.merged {
-		if !v.Scientific {
-			// Check spelling for common names
-			v.checkSpelling(e.speller)
+		if v.Hybrid {
+			v.Scientific = v.Parents[0].Scientific && v.Parents[1].Scientific
+		}
+	}
+	e.checkSpelling()
+}
+
+func (e *extractor) checkSpelling() {
+	// Checks spelling of terms which were not classified as scientific names
+	for _, v := range e.merged {
+		terms := []*Term{v}
+		if v.Hybrid {
+			terms = v.Parents
+		}
+		for _, i := range terms {
+			if !i.Scientific {
+				// Check spelling for common names
+				i.checkSpelling(e.spellers...)
+			}
+		}
+	}
+}
+
+func (e *extractor) expandTerm(g *genera, t *Term) bool {
+	// Replaces abbreviated genus of term (or of hybrid parents) with a genus from the input
+	var ret bool
+	terms := []*Term{t}
+	if t.Hybrid {
+		terms = t.Parents
+	}
+	for _, i := range terms {
+		if v, ok := g.expand(i.Term); ok {
+			i.Term = v
+			i.Scientific = true
+			ret = true
 		}
 	}
+	if ret && t.Hybrid {
+		t.Term = t.Parents[0].Term + " x " + t.Parents[1].Term
+		t.Scientific = t.Parents[0].Scientific && t.Parents[1].Scientific
+	}
+	return ret
+}
+
+func (e *extractor) expandAbbreviations() {
+	// Expands abbreviated genera using other names in the input
+	var count int
+	all := e.names
+	for _, i := range e.names {
+		all = append(all[:len(all):len(all)], i.Parents...)
+	}
+	g := newGenera(all)
+	for _, i := range e.names {
+		if e.expandTerm(g, i) {
+			count++
+		}
+	}
+	if count > 0 {
+		e.logger.Printf("Expanded abbreviated genera in %d entries.\n", count)
+	}
 }
 
 func (e *extractor) mergeTerms() {
 	// Merges terms which format to same spelling and tries to resolve abbreviations
+	e.expandAbbreviations()
 	for _, i := range e.names {
-		if _, ex := e.merged[i.Term]; ex {
-			i.AddQuery(i.Queries[0])
+		if v, ex := e.merged[i.Term]; ex {
+			v.AddQuery(i.Queries[0])
+			v.mergeAnnotations(i)
 		} else {
 			e.merged[i.Term] = i
 		}
@@ -135,52 +255,94 @@ func (e *extractor) mergeTerms() {
 
 func (e *extractor) filterTerms() {
 	// Reads terms from given column and checks formatting
-	first := true
-	var d string
-	f := iotools.OpenFile(e.infile)
-	defer f.Close()
-	scanner := iotools.GetScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(string(scanner.Text()))
-		if !first {
-			var query string
-			if e.col >= 0 {
-				s := strings.Split(line, d)
-				if len(s) > e.col {
-					query = s[e.col]
-				}
-			} else {
-				query = line
+	reader, err := kestrelutils.NewReader(e.infile, true)
+	if err != nil {
+		e.logger.Printf("[Error] Cannot read %s: %v\n", e.infile, err)
+		os.Exit(1)
+	}
+	defer reader.Close()
+	col, err := reader.Column(e.col)
+	if err != nil {
+		e.logger.Printf("[Error] %v\n", err)
+		os.Exit(1)
+	}
+	for {
+		s, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			if kestrelutils.IsParseError(err) {
+				continue
 			}
-			if query != "" {
-				t := NewTerm(query)
-				if len(t.Queries) >= 1 {
-					t.filter()
-					// Append terms with no fail reason to pass; else append to fail
-					if len(t.Status) == 0 {
-						e.names = append(e.names, t)
-					} else {
-						e.fail = append(e.fail, []string{t.Queries[0], t.Term, t.Status})
-					}
-				}
+			e.logger.Printf("[Error] Cannot read %s: %v\n", e.infile, err)
+			os.Exit(1)
+		}
+		var query string
+		if col >= 0 {
+			if len(s) > col {
+				query = s[col]
 			}
 		} else {
-			d, _ = iotools.GetDelim(line)
-			first = false
+			// Keep entire line for single column files
+			query = strings.Join(s, string(reader.Delim))
 		}
+		e.filterQuery(query)
+	}
+}
+
+func (e *extractor) filterQuery(query string) {
+	// Formats query and stores it as a search term or rejected term
+	if query != "" {
+		t := NewTerm(query)
+		if len(t.Queries) >= 1 {
+			t.filter(e.options)
+			// Append terms with no fail reason to pass; else append to rejected
+			if len(t.Status) == 0 {
+				e.names = append(e.names, t)
+			} else {
+				e.rejected = append(e.rejected, t)
+			}
+		}
+	}
+}
+
+func (e *extractor) writeRejected() {
+	// Writes rejected terms and reasons to file
+	var rows [][]string
+	for _, t := range e.rejected {
+		rows = append(rows, []string{t.Queries[0], t.Term, t.Status})
+	}
+	if err := kestrelutils.WriteCSV(e.misses, []string{"Query", "SearchTerm", "Reason"}, rows); err != nil {
+		e.logger.Printf("[Error] Cannot write rejected terms: %v\n", err)
 	}
 }
 
-func ExtractSearchTerms(infile, outfile string, col int, logger *log.Logger) map[string]*Term {
-	// Extracts and formats input terms
-	e := newExtractor(infile, outfile, col, logger)
+func ExtractSearchTerms(infile, outfile, col string, options *Options, logger *log.Logger) map[string]*Term {
+	// Extracts and formats input terms with given options
+	kestrelutils.CheckFile(infile)
+	e := newExtractor(infile, outfile, col, options, logger)
 	e.filterTerms()
 	e.logger.Printf("Successfully formatted %d entries.", len(e.names))
-	e.logger.Printf("%d entries failed formatting.", len(e.fail))
-	if len(e.fail) > 0 {
-		iotools.WriteToCSV(e.misses, "Query,SearchTerm,Reason", e.fail)
+	e.logger.Printf("%d entries failed formatting.", len(e.rejected))
+	if len(e.rejected) > 0 {
+		e.writeRejected()
 	}
 	e.mergeTerms()
 	e.classifyTerms()
 	return e.merged
 }
+
+func FormatTerms(queries []string, classify bool, options *Options, logger *log.Logger) (map[string]*Term, []*Term) {
+	// Formats queries in memory with given options and returns merged search terms and rejected terms
+	e := newExtractor("", "", "", options, logger)
+	for _, i := range queries {
+		e.filterQuery(i)
+	}
+	e.mergeTerms()
+	if classify {
+		e.classifyTerms()
+	} else {
+		e.checkSpelling()
+	}
+	return e.merged, e.rejected
+}
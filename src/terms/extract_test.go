@@ -36,17 +36,23 @@ func newExtractInput() []extractinput {
 	ret = append(ret, extractentry([]string{"unknown fish", "", "", "uncertainEntry"}))
 	ret = append(ret, extractentry([]string{"ferret?", "", "", "uncertainEntry"}))
 	ret = append(ret, extractentry([]string{"canine mix", "", "", "hybrid"}))
-	ret = append(ret, extractentry([]string{"corgi x", "", "", "hybrid"}))
+	ret = append(ret, extractentry([]string{"corgi x", "Canis familiaris", "", ""}))
+	ret = append(ret, extractentry([]string{"sheep goat hybrid", "", "", "hybrid"}))
+	ret = append(ret, extractentry([]string{"sheep x goat", "Sheep x Goat", "", ""}))
 	ret = append(ret, extractentry([]string{"xy", "", "", "tooShort"}))
 	return ret
 }
 
 func TestFilter(t *testing.T) {
 	speller, _ := aspell.NewSpeller(map[string]string{"lang": "en_US"})
+	o := NewOptions()
+	if err := o.LoadBreeds(BREEDFILE); err != nil {
+		t.Fatal(err)
+	}
 	exp := newExtractInput()
 	for _, e := range exp {
 		a := NewTerm(e.query)
-		a.filter()
+		a.filter(o)
 		a.checkSpelling(speller)
 		if len(e.status) > 0 {
 			if a.Status != e.status {
@@ -58,6 +64,14 @@ func TestFilter(t *testing.T) {
 			t.Errorf("%s actual spell-checked term %s does not equal expected: %s", e.query, a.Corrected, e.corrected)
 		}
 	}
+	// Crosses between species are parsed as hybrids instead of rejected
+	a := NewTerm("sheep x goat")
+	a.filter(o)
+	if !a.Hybrid || len(a.Parents) != 2 {
+		t.Errorf("sheep x goat was not parsed into two parents.")
+	} else if a.Parents[0].Term != "Sheep" || a.Parents[1].Term != "Goat" {
+		t.Errorf("Actual parents %s and %s do not equal expected: Sheep and Goat", a.Parents[0].Term, a.Parents[1].Term)
+	}
 }
 
 func TestTitleCase(t *testing.T) {
@@ -0,0 +1,93 @@
+// Defines hybrid formula parsing and parent taxonomies
+
+package terms
+
+import (
+	"github.com/icwells/go-tools/strarray"
+	"github.com/icwells/kestrel/src/taxonomy"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// HYBRID matches the cross symbol between parents of a hybrid formula
+var HYBRID = regexp.MustCompile(`(?i)\s+x\s+|\s*×\s*`)
+
+func HybridHeader() []string {
+	// Returns hybrid column names
+	return []string{"Hybrid", "Parents"}
+}
+
+func (t *Term) parseHybrid(o *Options) bool {
+	// Stores parent terms of hybrid formulas with exactly two valid parents
+	s := HYBRID.Split(strings.TrimSpace(t.Term), -1)
+	if len(s) != 2 {
+		return false
+	}
+	var parents []*Term
+	for _, i := range s {
+		p := NewTerm(i)
+		p.filter(o)
+		if len(p.Status) > 0 || p.Hybrid {
+			return false
+		}
+		parents = append(parents, p)
+	}
+	t.Parents = parents
+	t.Hybrid = true
+	t.Term = parents[0].Term + " x " + parents[1].Term
+	t.Scientific = parents[0].Scientific && parents[1].Scientific
+	return true
+}
+
+func (t *Term) SetHybrid() {
+	// Stores lowest common taxonomy, sources, and confidence of parents
+	var taxa []*taxonomy.Taxonomy
+	var citations []string
+	sources := make(map[string]bool)
+	t.Confirmed = true
+	t.Confidence = 1.0
+	for _, i := range t.Parents {
+		taxa = append(taxa, i.Taxonomy)
+		if !i.Confirmed {
+			t.Confirmed = false
+		}
+		if i.Confidence < t.Confidence {
+			t.Confidence = i.Confidence
+		}
+		for _, s := range i.Sources {
+			sources[s] = true
+		}
+		if v := i.Taxonomy.Source; v != "" && !strarray.InSliceStr(citations, v) {
+			citations = append(citations, v)
+		}
+	}
+	t.Taxonomy = taxonomy.LowestCommon(taxa...)
+	t.Taxonomy.Source = strings.Join(citations, "; ")
+	t.Sources = nil
+	for k := range sources {
+		t.Sources = append(t.Sources, k)
+	}
+	sort.Strings(t.Sources)
+}
+
+func (t *Term) parentNames() []string {
+	// Returns species (or search term if no species was found) of each parent
+	var ret []string
+	for _, i := range t.Parents {
+		if v := i.Taxonomy.Species; v != "" && v != "NA" {
+			ret = append(ret, v)
+		} else {
+			ret = append(ret, i.Term)
+		}
+	}
+	return ret
+}
+
+func (t *Term) hybridValues() []string {
+	// Returns hybrid status and parent names
+	if t.Hybrid {
+		return []string{"yes", strings.Join(t.parentNames(), ";")}
+	}
+	return []string{"no", ""}
+}
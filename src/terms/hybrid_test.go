@@ -0,0 +1,53 @@
+// Tests hybrid formula parsing
+
+package terms
+
+import (
+	"github.com/icwells/kestrel/src/taxonomy"
+	"testing"
+)
+
+func TestParseHybrid(t *testing.T) {
+	input := []struct {
+		query   string
+		hybrid  bool
+		parents int
+		status  string
+	}{
+		{"Canis lupus x Canis latrans", true, 2, ""},
+		{"Lion × tiger", true, 2, ""},
+		{"canine mix", false, 0, "hybrid"},
+		{"lion tiger hybrid", false, 0, "hybrid"},
+		{"fish x ?", false, 0, "uncertainEntry"},
+	}
+	for _, i := range input {
+		a := NewTerm(i.query)
+		a.filter(NewOptions())
+		if a.Hybrid != i.hybrid || len(a.Parents) != i.parents || a.Status != i.status {
+			t.Errorf("Actual hybrid %v with %d parents and status %q for %s does not equal expected: %v, %d, %q", a.Hybrid, len(a.Parents), a.Status, i.query, i.hybrid, i.parents, i.status)
+		}
+	}
+}
+
+func TestSetHybrid(t *testing.T) {
+	a := NewTerm("Canis lupus x Canis latrans")
+	a.filter(NewOptions())
+	for idx, i := range []string{"Canis lupus", "Canis latrans"} {
+		p := a.Parents[idx]
+		p.Taxonomy = taxonomy.NewTaxonomy()
+		p.Taxonomy.Kingdom, p.Taxonomy.Family, p.Taxonomy.Genus, p.Taxonomy.Species = "Animalia", "Canidae", "Canis", i
+		p.Confidence = 1.0 - 0.1*float64(idx)
+		p.Confirmed = true
+		p.Sources = []string{"ITIS"}
+	}
+	a.SetHybrid()
+	if a.Taxonomy.Genus != "Canis" || a.Taxonomy.Species != "NA" {
+		t.Errorf("Actual taxonomy %s does not equal expected: Canis", a.Taxonomy.String())
+	}
+	if a.Confidence != 0.9 || !a.Confirmed || len(a.Sources) != 1 {
+		t.Errorf("Actual confidence %f, confirmation %v, and sources %v do not equal expected: 0.9, true, [ITIS]", a.Confidence, a.Confirmed, a.Sources)
+	}
+	if v := a.hybridValues(); v[0] != "yes" || v[1] != "Canis lupus;Canis latrans" {
+		t.Errorf("Actual hybrid values %v do not equal expected: [yes Canis lupus;Canis latrans]", v)
+	}
+}
@@ -0,0 +1,41 @@
+// Defines options used to format search terms
+
+package terms
+
+import (
+	"github.com/icwells/kestrel/src/kestrelutils"
+)
+
+type Options struct {
+	Languages   []string
+	breeds      *breeds
+	descriptors *vocabulary
+}
+
+func NewOptions() *Options {
+	// Returns options for English common names and the default age descriptors without breeds
+	o := new(Options)
+	o.Languages = []string{"English"}
+	o.breeds = newBreeds()
+	o.descriptors = newVocabulary(map[string][]string{"Age": {"fetus", "juvenile", "infant"}})
+	return o
+}
+
+func (o *Options) SetLanguages(languages string) error {
+	// Stores comma-seperated languages (or all) for searches; English is used if languages is empty
+	var err error
+	o.Languages, err = kestrelutils.ParseLanguages(languages)
+	return err
+}
+
+func (o *Options) KeepLanguage(language string) bool {
+	// Returns true if common names in language should be used in searches
+	return kestrelutils.KeepLanguage(o.Languages, language)
+}
+
+func (o *Options) Format(query string) *Term {
+	// Returns query filtered with options without spelling correction
+	t := NewTerm(query)
+	t.filter(o)
+	return t
+}
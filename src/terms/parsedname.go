@@ -0,0 +1,281 @@
+// Defines structured scientific names with authorship and year
+
+package terms
+
+import (
+	"github.com/icwells/kestrel/src/taxonomy"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// CONNECTORS stores lower case words which may occur within an authority
+	CONNECTORS = map[string]bool{"&": true, "al": true, "and": true, "d'": true, "da": true, "de": true, "del": true, "der": true, "du": true, "et": true, "ex": true, "in": true, "la": true, "le": true, "van": true, "von": true}
+	// EPITHET matches lower case specific and infraspecific epithets
+	EPITHET = regexp.MustCompile(`^[a-z][a-z-]+$`)
+	// GENUS matches capitalized genus names
+	GENUS = regexp.MustCompile(`^[A-Z][a-z]+$`)
+	// YEAR matches years of publication
+	YEAR = regexp.MustCompile(`^(1[5-9][0-9]{2}|20[0-9]{2})$`)
+)
+
+type ParsedName struct {
+	Authorship    string
+	Epithets      []string
+	Genus         string
+	Parenthetical bool
+	Year          string
+}
+
+func caseless(words []string) bool {
+	// Returns true if words are all upper or all lower case so case cannot distinguish epithets from authors
+	s := strings.Join(words, " ")
+	return s == strings.ToLower(s) || s == strings.ToUpper(s)
+}
+
+func capitalized(word string) bool {
+	// Returns true if word begins with an upper case letter
+	w := strings.TrimLeft(word, "([")
+	return w != "" && unicode.IsUpper([]rune(w)[0])
+}
+
+func title(word string) string {
+	// Returns word in lower case with its first letter capitalized
+	r := []rune(strings.ToLower(word))
+	for idx, i := range r {
+		if unicode.IsLetter(i) {
+			r[idx] = unicode.ToUpper(i)
+			break
+		}
+	}
+	return string(r)
+}
+
+func authorityWord(word string) bool {
+	// Returns true if word may be part of an authority
+	w := strings.Trim(word, "()[],;.")
+	return w == "" || YEAR.MatchString(w) || CONNECTORS[strings.ToLower(w)] || capitalized(w)
+}
+
+func knownAuthority(words []string) bool {
+	// Returns true if words contain an abbreviated author or multiple authors
+	for idx, i := range words {
+		w := strings.Trim(i, "()[],;")
+		if strings.HasSuffix(w, ".") || w == "&" || (strings.ToLower(w) == "et" && idx < len(words)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ParsedName) setAuthority(words []string, titlecase bool) {
+	// Stores authorship and year from words following the epithets
+	s := strings.TrimSpace(strings.Join(words, " "))
+	if strings.HasPrefix(s, "(") || strings.HasPrefix(s, "[") {
+		p.Parenthetical = true
+	}
+	var authors []string
+	for _, i := range strings.Fields(strings.NewReplacer("(", " ", ")", " ", "[", " ", "]", " ").Replace(s)) {
+		if y := strings.Trim(i, ",;."); YEAR.MatchString(y) {
+			p.Year = y
+		} else {
+			if titlecase && !CONNECTORS[strings.ToLower(strings.Trim(i, ",;."))] {
+				// Restore capitalization of authors given in a single case
+				i = title(i)
+			}
+			authors = append(authors, i)
+		}
+	}
+	p.Authorship = strings.Trim(strings.Join(authors, " "), ", ;")
+}
+
+func (p *ParsedName) setEpithets(words []string) bool {
+	// Stores one or two epithets; returns false if words are not epithets
+	if len(words) == 0 || len(words) > 2 {
+		return false
+	}
+	for _, i := range words {
+		if !EPITHET.MatchString(i) || CONNECTORS[i] || QUALIFIERS[i] != "" {
+			return false
+		}
+	}
+	p.Epithets = words
+	return true
+}
+
+func yearIndex(words []string) int {
+	// Returns index of final year if it is the last word, or -1
+	if n := len(words) - 1; n >= 0 && YEAR.MatchString(strings.Trim(words[n], "()[],;.")) {
+		return n
+	}
+	return -1
+}
+
+func authorStart(orig []string, year int, nocase bool) int {
+	// Returns index of the first author word preceding the year (leaving at least a genus and epithet)
+	ret := year
+	for idx := year - 1; idx >= 2; idx-- {
+		w := orig[idx]
+		if strings.HasPrefix(w, "(") || strings.HasPrefix(w, "[") {
+			return idx
+		} else if nocase {
+			// Without case, only the word before the year can be identified as its author
+			if ret == year {
+				ret = idx
+			}
+			break
+		} else if authorityWord(w) {
+			ret = idx
+		} else {
+			break
+		}
+	}
+	return ret
+}
+
+func parseName(orig, caps string, scientific bool) *ParsedName {
+	// Returns structured name from original and capitalized terms; authorities without a year or known pattern are only kept for scientific names
+	words, lower := strings.Fields(orig), strings.Fields(caps)
+	if len(words) < 2 || len(words) != len(lower) || !GENUS.MatchString(lower[0]) {
+		return nil
+	}
+	p := new(ParsedName)
+	p.Genus = lower[0]
+	nocase := caseless(words)
+	idx := 1
+	if year := yearIndex(words); year >= 2 {
+		idx = authorStart(words, year, nocase)
+	} else {
+		for idx < len(words) && idx < 3 && (nocase || !capitalized(words[idx])) && EPITHET.MatchString(lower[idx]) {
+			idx++
+		}
+		if rest := words[idx:]; len(rest) > 0 && !knownAuthority(rest) && (nocase || !scientific) {
+			// Capitalized words following common names are not authorities
+			return nil
+		}
+	}
+	if !nocase {
+		// Epithets are lower case and authors are capitalized when case is given
+		for i := 1; i < len(words); i++ {
+			if (i < idx && capitalized(words[i])) || (i >= idx && !authorityWord(words[i])) {
+				return nil
+			}
+		}
+	}
+	if !p.setEpithets(lower[1:idx]) {
+		return nil
+	}
+	p.setAuthority(words[idx:], nocase)
+	return p
+}
+
+func ParseName(name string) *ParsedName {
+	// Returns structured name if name is a genus followed by up to two epithets and an optional authority with a year or abbreviation
+	return parseName(name, new(taxonomy.Taxonomy).SpeciesCaps(name), false)
+}
+
+func (p *ParsedName) String() string {
+	// Returns genus and epithets
+	return strings.Join(append([]string{p.Genus}, p.Epithets...), " ")
+}
+
+func (p *ParsedName) HasAuthority() bool {
+	// Returns true if authorship or year were given
+	return p.Authorship != "" || p.Year != ""
+}
+
+func (p *ParsedName) Authority() string {
+	// Returns authorship and year in standard format
+	var ret string
+	if p.Authorship != "" && p.Year != "" {
+		ret = p.Authorship + ", " + p.Year
+	} else {
+		ret = p.Authorship + p.Year
+	}
+	if p.Parenthetical && ret != "" {
+		ret = "(" + ret + ")"
+	}
+	return ret
+}
+
+func (p *ParsedName) score(citation string) int {
+	// Returns number of authority components found in citation
+	var ret int
+	citation = strings.ToLower(citation)
+	if p.Year != "" && strings.Contains(citation, p.Year) {
+		ret++
+	}
+	if s := strings.Fields(strings.Trim(p.Authorship, ".")); len(s) > 0 {
+		author := strings.ToLower(strings.Trim(s[0], ",.&"))
+		if author == "l" {
+			// Linnaeus is usually abbreviated
+			author = "linnaeus"
+		}
+		if len(author) > 1 && strings.Contains(citation, author) {
+			ret++
+		}
+	}
+	return ret
+}
+
+func (p *ParsedName) Choose(taxa []*taxonomy.Taxonomy) *taxonomy.Taxonomy {
+	// Returns the only taxonomy whose citation best matches the authority, or nil if none match or the best match is tied
+	var ret *taxonomy.Taxonomy
+	var max int
+	if p == nil || !p.HasAuthority() {
+		return ret
+	}
+	for _, i := range taxa {
+		if s := p.score(i.Source); s > max {
+			max = s
+			ret = i
+		} else if s == max && s > 0 {
+			ret = nil
+		}
+	}
+	return ret
+}
+
+func AuthorityHeader() []string {
+	// Returns authority column name
+	return []string{"Authority"}
+}
+
+func (t *Term) parseName(orig string) {
+	// Stores structured name and removes the authority from capitalized terms
+	if p := parseName(orig, t.Term, t.Scientific); p != nil {
+		t.Name = p
+		if p.HasAuthority() {
+			t.Term = p.String()
+		}
+	}
+}
+
+func (t *Term) scientificName() {
+	// Parses authorities without a year or abbreviation once term has been classified as a scientific name
+	if t.Name != nil && t.Name.HasAuthority() {
+		return
+	}
+	q := strings.Join(strings.Fields(t.Queries[0]), " ")
+	if p := parseName(q, t.Taxonomy.SpeciesCaps(q), true); p != nil && p.HasAuthority() && strings.HasPrefix(t.Term+" ", p.String()+" ") {
+		t.Name = p
+		t.Term = p.String()
+	}
+}
+
+func (t *Term) nameLength() int {
+	// Returns number of words in parsed scientific name (binomial by default)
+	if t.Name != nil && t.Name.String() == t.Term {
+		return len(t.Name.Epithets) + 1
+	}
+	return 2
+}
+
+func (t *Term) Authority() string {
+	// Returns authority given in the query
+	if t.Name != nil {
+		return t.Name.Authority()
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+// Tests structured scientific names with authorship and year
+
+package terms
+
+import (
+	"github.com/icwells/kestrel/src/taxonomy"
+	"testing"
+)
+
+func TestParseName(t *testing.T) {
+	input := []struct {
+		query     string
+		name      string
+		authority string
+		ok        bool
+	}{
+		{"Canis lupus Linnaeus, 1758", "Canis lupus", "Linnaeus, 1758", true},
+		{"Vulpes vulpes (Linnaeus, 1758)", "Vulpes vulpes", "(Linnaeus, 1758)", true},
+		{"Felis concolor (L.)", "Felis concolor", "(L.)", true},
+		{"Canis lupus familiaris Linnaeus, 1758", "Canis lupus familiaris", "Linnaeus, 1758", true},
+		{"Puma concolor (Linnaeus 1771)", "Puma concolor", "(Linnaeus, 1771)", true},
+		{"Canis latrans", "Canis latrans", "", true},
+		{"canis lupus linnaeus 1758", "Canis lupus", "Linnaeus, 1758", true},
+		{"CANIS LUPUS (LINNAEUS, 1758)", "Canis lupus", "(Linnaeus, 1758)", true},
+		{"Canis lupus familiaris 1758", "Canis lupus familiaris", "1758", true},
+		{"Canis lupus Linnaeus", "", "", false},
+		{"Gray fox (Frank)", "", "", false},
+		{"Gray Fox 1999", "", "", false},
+		{"Gray Wolf", "", "", false},
+		{"coyote", "", "", false},
+		{"Anolis cf. carolinensis", "", "", false},
+	}
+	for _, i := range input {
+		p := ParseName(i.query)
+		if (p != nil) != i.ok {
+			t.Errorf("Actual parsed status %v for %s does not equal expected: %v", p != nil, i.query, i.ok)
+		} else if p != nil && (p.String() != i.name || p.Authority() != i.authority) {
+			t.Errorf("Actual name %s with authority %q does not equal expected: %s, %q", p.String(), p.Authority(), i.name, i.authority)
+		}
+	}
+}
+
+func TestFilterAuthority(t *testing.T) {
+	input := []struct {
+		query     string
+		term      string
+		authority string
+	}{
+		{"Vulpes vulpes (Linnaeus, 1758)", "Vulpes vulpes", "(Linnaeus, 1758)"},
+		{"canis lupus linnaeus 1758", "Canis lupus", "Linnaeus, 1758"},
+		{"Gray fox (Frank)", "Gray fox", ""},
+	}
+	for _, i := range input {
+		a := NewTerm(i.query)
+		a.filter(NewOptions())
+		if a.Term != i.term || a.Authority() != i.authority {
+			t.Errorf("Actual term %s with authority %q does not equal expected: %s, %q", a.Term, a.Authority(), i.term, i.authority)
+		}
+	}
+	// Authorities without a year or abbreviation are kept once the term is classified as scientific
+	a := NewTerm("Canis lupus Linnaeus")
+	a.filter(NewOptions())
+	if a.Authority() != "" {
+		t.Errorf("Actual authority %s of unclassified term does not equal expected: \"\"", a.Authority())
+	}
+	a.Scientific = true
+	a.scientificName()
+	if a.Term != "Canis lupus" || a.Authority() != "Linnaeus" {
+		t.Errorf("Actual term %s with authority %q does not equal expected: Canis lupus, Linnaeus", a.Term, a.Authority())
+	}
+}
+
+func TestChoose(t *testing.T) {
+	older, newer := taxonomy.NewTaxonomy(), taxonomy.NewTaxonomy()
+	older.Source, older.Genus = "Smith, 1850", "Alpha"
+	newer.Source, newer.Genus = "Jones, 1901", "Beta"
+	taxa := []*taxonomy.Taxonomy{older, newer}
+	input := []struct {
+		query string
+		genus string
+	}{
+		{"Testus name Jones, 1901", "Beta"},
+		{"Testus name (Smith)", "Alpha"},
+		{"Testus name 1850", "Alpha"},
+		{"Testus name Brown, 1920", ""},
+		{"Testus name", ""},
+	}
+	for _, i := range input {
+		var act string
+		if v := parseName(i.query, i.query, true).Choose(taxa); v != nil {
+			act = v.Genus
+		}
+		if act != i.genus {
+			t.Errorf("Actual chosen genus %q for %s does not equal expected: %q", act, i.query, i.genus)
+		}
+	}
+}
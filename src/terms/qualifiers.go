@@ -0,0 +1,102 @@
+// Defines open nomenclature qualifiers and genus abbreviation expansion
+
+package terms
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// ABBREVIATION matches binomials and trinomials with an abbreviated genus
+	ABBREVIATION = regexp.MustCompile(`^([A-Z][a-z]?)\. ([a-z][a-z-]*(?: [a-z][a-z-]*)?)$`)
+	// QUALIFIERS stores open nomenclature qualifiers by lower case abbreviation without the period
+	QUALIFIERS = map[string]string{"sp": "sp.", "spp": "spp.", "cf": "cf.", "aff": "aff."}
+)
+
+func QualifierHeader() []string {
+	// Returns qualifier column name
+	return []string{"Qualifier"}
+}
+
+func (t *Term) parseQualifier() {
+	// Removes open nomenclature qualifier from term and stores it in t.Qualifier
+	words := strings.Fields(t.Term)
+	for idx := 1; idx < len(words); idx++ {
+		if q, ex := QUALIFIERS[strings.ToLower(strings.TrimSuffix(words[idx], "."))]; ex {
+			t.Qualifier = q
+			if t.GenusOnly() {
+				// Words following genus-level qualifiers are specimen labels
+				t.Term = strings.Join(words[:idx], " ")
+			} else {
+				t.Term = strings.Join(append(words[:idx:idx], words[idx+1:]...), " ")
+			}
+			t.Scientific = true
+			return
+		}
+	}
+}
+
+func (t *Term) GenusOnly() bool {
+	// Returns true if term should be resolved at genus level
+	return t.Qualifier == "sp." || t.Qualifier == "spp."
+}
+
+func (t *Term) Uncertain() bool {
+	// Returns true if identification was qualified as uncertain
+	return t.Qualifier == "cf." || t.Qualifier == "aff."
+}
+
+func (t *Term) Qualify() {
+	// Applies qualifier to matched taxonomy
+	if t.GenusOnly() {
+		t.Taxonomy.Truncate("genus")
+	} else if t.Uncertain() {
+		t.Confirmed = false
+	}
+}
+
+type genera struct {
+	names map[string]bool
+	terms map[string]bool
+}
+
+func newGenera(terms []*Term) *genera {
+	// Stores first words of unabbreviated names with more than one word
+	g := new(genera)
+	g.names = make(map[string]bool)
+	g.terms = make(map[string]bool)
+	for _, t := range terms {
+		if s := strings.Fields(t.Term); len(s) > 1 && !ABBREVIATION.MatchString(t.Term) && !strings.HasSuffix(s[0], ".") {
+			g.names[s[0]] = true
+			g.terms[t.Term] = true
+		}
+	}
+	return g
+}
+
+func (g *genera) expand(term string) (string, bool) {
+	// Returns term with abbreviated genus replaced by the only matching genus (preferring genera with the same epithet)
+	m := ABBREVIATION.FindStringSubmatch(term)
+	if m == nil {
+		return term, false
+	}
+	var exact, partial []string
+	for k := range g.names {
+		if strings.HasPrefix(k, m[1]) {
+			if g.terms[k+" "+m[2]] {
+				exact = append(exact, k)
+			}
+			partial = append(partial, k)
+		}
+	}
+	for _, i := range [][]string{exact, partial} {
+		if len(i) == 1 {
+			return i[0] + " " + m[2], true
+		} else if len(i) > 1 {
+			// Leave ambiguous abbreviations unchanged
+			break
+		}
+	}
+	return term, false
+}
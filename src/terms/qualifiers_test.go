@@ -0,0 +1,49 @@
+// Tests open nomenclature qualifiers and genus abbreviations
+
+package terms
+
+import (
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"testing"
+)
+
+func TestParseQualifier(t *testing.T) {
+	input := []struct {
+		query     string
+		term      string
+		qualifier string
+		genus     bool
+		uncertain bool
+	}{
+		{"Canis sp.", "Canis", "sp.", true, false},
+		{"PYTHON SPP", "Python", "spp.", true, false},
+		{"Canis sp. 2", "Canis", "sp.", true, false},
+		{"Anolis cf. carolinensis", "Anolis carolinensis", "cf.", false, true},
+		{"Canis aff lupus", "Canis lupus", "aff.", false, true},
+		{"Canis lupus", "Canis lupus", "", false, false},
+	}
+	for _, i := range input {
+		a := NewTerm(i.query)
+		a.filter(NewOptions())
+		if a.Term != i.term || a.Qualifier != i.qualifier {
+			t.Errorf("Actual term %s with qualifier %q does not equal expected: %s, %q", a.Term, a.Qualifier, i.term, i.qualifier)
+		} else if a.GenusOnly() != i.genus || a.Uncertain() != i.uncertain {
+			t.Errorf("Actual genus-only %v and uncertain %v for %s do not equal expected: %v, %v", a.GenusOnly(), a.Uncertain(), i.query, i.genus, i.uncertain)
+		}
+	}
+}
+
+func TestExpandAbbreviations(t *testing.T) {
+	merged, _ := FormatTerms([]string{"Canis lupus", "Canis latrans", "Cape buffalo", "C. latrans", "C. familiaris", "Urocyon cinereoargenteus", "Gray fox x U. littoralis"}, false, NewOptions(), kestrelutils.GetLogger())
+	if v, ex := merged["Canis latrans"]; !ex || len(v.Queries) != 2 {
+		t.Errorf("C. latrans was not merged with Canis latrans.")
+	}
+	if _, ex := merged["C. familiaris"]; !ex {
+		t.Errorf("Ambiguous abbreviation C. familiaris was expanded.")
+	}
+	for k, v := range merged {
+		if v.Hybrid && v.Parents[1].Term != "Urocyon littoralis" {
+			t.Errorf("Actual hybrid parent %s in %s does not equal expected: Urocyon littoralis", v.Parents[1].Term, k)
+		}
+	}
+}
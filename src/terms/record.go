@@ -0,0 +1,70 @@
+// Defines structured search result record
+
+package terms
+
+import (
+	"encoding/json"
+	"github.com/icwells/kestrel/src/kestrelutils"
+	"github.com/icwells/kestrel/src/taxonomy"
+)
+
+type Record struct {
+	Query       string            `json:"query"`
+	Term        string            `json:"term"`
+	Ranks       map[string]string `json:"ranks"`
+	Identifiers map[string]string `json:"identifiers,omitempty"`
+	Sources     []string          `json:"sources"`
+	Confidence  float64           `json:"confidence"`
+	CITES       string            `json:"cites,omitempty"`
+	Hybrid      bool              `json:"hybrid,omitempty"`
+	Parents     []string          `json:"parents,omitempty"`
+	Qualifier   string            `json:"qualifier,omitempty"`
+	Authority   string            `json:"authority,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Status      string            `json:"status"`
+}
+
+func (t *Term) Record(query string, found bool, o *taxonomy.Output) Record {
+	// Returns structured record for given query with output levels
+	var r Record
+	r.Query = query
+	r.Term = kestrelutils.PercentDecode(t.Term)
+	r.Ranks = make(map[string]string)
+	r.Sources = []string{}
+	r.Status = "missed"
+	r.Qualifier = t.Qualifier
+	r.Authority = t.Authority()
+	r.Annotations = t.annotationMap(query)
+	if t.Hybrid {
+		r.Hybrid = true
+		r.Parents = t.parentNames()
+	}
+	if found {
+		r.Status = "unconfirmed"
+		if t.Confirmed {
+			r.Status = "confirmed"
+		}
+		for _, i := range o.Levels {
+			r.Ranks[i] = t.Taxonomy.Get(i)
+		}
+		if len(t.Taxonomy.Identifiers) > 0 {
+			r.Identifiers = t.Taxonomy.Identifiers
+		}
+		if len(t.Sources) > 0 {
+			r.Sources = t.Sources
+		} else if t.Taxonomy.Source != "" {
+			r.Sources = []string{t.Taxonomy.Source}
+		}
+		r.Confidence = t.Confidence
+		if v := o.CITESValues(t.Taxonomy); len(v) > 0 {
+			r.CITES = v[0]
+		}
+	}
+	return r
+}
+
+func (t *Term) JSON(query string, found bool, o *taxonomy.Output) string {
+	// Returns record as a single line of json
+	ret, _ := json.Marshal(t.Record(query, found, o))
+	return string(ret)
+}
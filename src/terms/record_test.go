@@ -0,0 +1,32 @@
+// Tests structured record output
+
+package terms
+
+import (
+	"encoding/json"
+	"github.com/icwells/kestrel/src/taxonomy"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	a := NewTerm("GRAY FOX (frank)")
+	a.Term = "Gray%20fox"
+	a.Taxonomy.Kingdom = "Animalia"
+	a.Taxonomy.Species = "Urocyon cinereoargenteus"
+	a.Taxonomy.Source = "Ord, 1815"
+	a.Confidence = 1.0
+	a.Confirm()
+	var r Record
+	if err := json.Unmarshal([]byte(a.JSON(a.Queries[0], true, taxonomy.NewOutput())), &r); err != nil {
+		t.Error(err)
+	} else if r.Term != "Gray fox" || r.Query != "GRAY FOX (frank)" {
+		t.Errorf("Actual query and term %s, %s do not equal expected: GRAY FOX (frank), Gray fox", r.Query, r.Term)
+	} else if r.Ranks["species"] != "Urocyon cinereoargenteus" || r.Ranks["phylum"] != "NA" {
+		t.Errorf("Actual ranks %v are incorrect.", r.Ranks)
+	} else if r.Status != "confirmed" || len(r.Sources) != 1 || r.Sources[0] != "Ord, 1815" {
+		t.Errorf("Actual status %s and sources %v are incorrect.", r.Status, r.Sources)
+	}
+	if r := a.Record(a.Queries[0], false, taxonomy.NewOutput()); r.Status != "missed" || len(r.Ranks) != 0 {
+		t.Errorf("Actual missed status %s and ranks %v are incorrect.", r.Status, r.Ranks)
+	}
+}
@@ -17,13 +17,20 @@ import (
 var MAXDIST = 2
 
 type Term struct {
-	Confirmed  bool
-	Corrected  string
-	Queries    []string
-	Scientific bool
-	Status     string
-	Taxonomy   *taxonomy.Taxonomy
-	Term       string
+	Annotations map[string]map[string][]string
+	Confidence  float64
+	Confirmed   bool
+	Corrected   string
+	Hybrid      bool
+	Name        *ParsedName
+	Parents     []*Term
+	Qualifier   string
+	Queries     []string
+	Scientific  bool
+	Sources     []string
+	Status      string
+	Taxonomy    *taxonomy.Taxonomy
+	Term        string
 }
 
 func NewTerm(query string) *Term {
@@ -36,17 +43,27 @@ func NewTerm(query string) *Term {
 	return t
 }
 
-func (t *Term) String() string {
-	// Returns formatted string
+func (t *Term) Slice(o *taxonomy.Output) []string {
+	// Returns term, taxonomy, and confirmation as output row
 	var ret []string
 	ret = append(ret, kestrelutils.PercentDecode(t.Term))
-	ret = append(ret, t.Taxonomy.String())
+	for _, i := range append(t.Taxonomy.Values(o.Levels), t.Taxonomy.Source) {
+		ret = append(ret, strings.Replace(i, `"`, "", -1))
+	}
 	if t.Confirmed {
 		ret = append(ret, "yes")
 	} else {
 		ret = append(ret, "no")
 	}
-	return strings.Join(ret, ",")
+	ret = append(ret, t.Taxonomy.IdentifierValues(o.Identifiers)...)
+	ret = append(ret, o.CITESValues(t.Taxonomy)...)
+	ret = append(ret, t.hybridValues()...)
+	return append(ret, t.Qualifier, t.Authority())
+}
+
+func (t *Term) String() string {
+	// Returns formatted string
+	return strings.Join(t.Slice(taxonomy.NewOutput()), ",")
 }
 
 func (t *Term) AddQuery(query string) {
@@ -59,14 +76,31 @@ func (t *Term) Confirm() {
 	t.Confirmed = true
 }
 
-func (t *Term) checkSpelling(speller aspell.Speller) {
-	// Stores potential corrected spelling in t.Corrected if word is incorrectly spelled
+func known(spellers []aspell.Speller, word string) bool {
+	// Returns true if word is spelled correctly in any language
+	for _, i := range spellers {
+		if i.Check(word) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Term) checkSpelling(spellers ...aspell.Speller) {
+	// Stores potential corrected spelling in t.Corrected if word is incorrectly spelled in every language
 	var builder strings.Builder
 	var pass bool
+	if len(spellers) == 0 {
+		return
+	}
 	for idx, i := range strings.Split(t.Term, " ") {
 		match := i
-		if !speller.Check(match) {
-			matches := fuzzy.RankFindFold(i, speller.Suggest(i))
+		if !known(spellers, match) {
+			var suggestions []string
+			for _, s := range spellers {
+				suggestions = append(suggestions, s.Suggest(i)...)
+			}
+			matches := fuzzy.RankFindFold(i, suggestions)
 			if matches.Len() > 0 {
 				sort.Sort(matches)
 				if matches[0].Distance <= MAXDIST {
@@ -181,26 +215,6 @@ func (t *Term) reformat() {
 	}
 }
 
-func (t *Term) removeInfant() {
-	// Removes words referring to infancy from term
-	if strings.Count(t.Term, " ") >= 1 {
-		var buffer strings.Builder
-		first := true
-		s := strings.Split(t.Term, " ")
-		words := "Fetus, Juvenile, Infant"
-		for _, i := range s {
-			if strings.Contains(words, i) == false {
-				if first == false {
-					buffer.WriteRune(' ')
-				}
-				buffer.WriteString(i)
-				first = false
-			}
-		}
-		t.Term = buffer.String()
-	}
-}
-
 func (t *Term) checkCertainty() {
 	// Sets t.Status if term is unknown or hybrid
 	unk := "uncertainEntry"
@@ -221,21 +235,31 @@ func (t *Term) speciesCaps() {
 	t.Term = t.Taxonomy.SpeciesCaps(t.Term)
 }
 
-func (t *Term) filter() {
-	// Filters input query
+func (t *Term) filter(o *Options) {
+	// Filters input query with given options
 	short := "tooShort"
 	query := t.Queries[0]
 	if len(query) >= 3 {
 		r := regexp.MustCompile(` +`)
 		// Replace extra spaces and convert to title case
 		t.Term = r.ReplaceAllString(query, " ")
+		t.removeDescriptors(o)
+		if t.checkBreed(o) {
+			// Breeds and breed crosses are searched as their domestic species
+			return
+		} else if t.parseHybrid(o) {
+			// Parents of hybrid formulas are searched seperately
+			return
+		}
 		t.checkCertainty()
 		if len(t.Status) == 0 {
 			// Convert to title case after checking for ? and x
+			orig := t.Term
 			t.speciesCaps()
-			t.removeInfant()
+			t.parseName(orig)
 			t.reformat()
 			t.checkRunes()
+			t.parseQualifier()
 			if len(t.Status) == 0 && len(t.Term) < 3 {
 				t.Status = short
 			}
@@ -16,6 +16,7 @@ SEARCHOUTPUT="$TEST/searchResults.csv"
 REJECTED="$TEST/KestrelRejected.csv"
 MISSED="$TEST/KestrelMissed.csv"
 
+KESTRELUTILS="$SRC/kestrelutils/*.go"
 SEARCHTAXA="$SRC/searchtaxa/*.go"
 TAXONOMY="$SRC/taxonomy/*.go"
 TERMS="$SRC/terms/*.go"
@@ -31,6 +32,7 @@ getUser () {
 whiteBoxTests () {
 	echo ""
 	echo "Running white box tests..."
+	go test $KESTRELUTILS
 	go test $SEARCHTAXA
 	go test $TAXONOMY
 	go test $TERMS
@@ -67,6 +69,7 @@ checkSource () {
 	echo ""
 	echo "Running go $1..."
 	go $1 "$SRC/main.go"
+	go $1 $KESTRELUTILS
 	go $1 $SEARCHTAXA
 	go $1 $TAXONOMY
 	go $1 $TERMS
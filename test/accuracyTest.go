@@ -21,7 +21,7 @@ import (
 var (
 	infile   = "../utils/corpus.csv.gz"
 	outfile  = "searchResults.csv"
-	col      = 0
+	col      = "0"
 	nocorpus = false
 	password = flag.String("password", "", "MySQL password.")
 	proc     = 50
@@ -123,13 +123,13 @@ func setExpected() *dataframe.Dataframe {
 			// Ignore rows without common names
 			break
 		}
-		if ex, _ := set.InSet(i[col]); !ex {
+		if ex, _ := set.InSet(i[0]); !ex {
 			exp = append(exp, i)
 		}
-		set.Add(i[col])
+		set.Add(i[0])
 	}
 	exp = append([][]string{head}, exp...)
-	ret, err := dataframe.FromSlice(exp, col)
+	ret, err := dataframe.FromSlice(exp, 0)
 	if err != nil {
 		panic(err)
 	}
@@ -143,10 +143,10 @@ func main() {
 	logger := kestrelutils.GetLogger()
 	db := kestrelutils.ConnectToDatabase(*user, *password, false)
 	logger.Println("Extracting search terms...")
-	searchterms := terms.ExtractSearchTerms(infile, outfile, col, logger)
+	searchterms := terms.ExtractSearchTerms(infile, outfile, col, terms.NewOptions(), logger)
 	logger.Printf("Current run time: %v\n", time.Since(start))
 	logger.Println("Searching for taxonomy matches...")
-	searchtaxa.SearchTaxonomies(db, outfile, subsetTerms(searchterms), proc, nocorpus, logger)
+	searchtaxa.SearchTaxonomies(searchtaxa.Config{DB: db, Logger: logger, NoCorpus: nocorpus, Processes: proc}, outfile, "csv", subsetTerms(searchterms))
 	logger.Printf("Finished. Run time: %v\n\n", time.Since(start))
 	logger.Println("Comparing output...")
 	exp := setExpected()
@@ -14,14 +14,18 @@ var (
 
 func expectedTaxa() [][]string {
 	return [][]string{
-		{"Query", "SearchTerm", "Kingdom", "Phylum", "Class", "Order", "Family", "Genus", "Species"},
-		{"Coyote", "Coyote", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis latrans"},
-		{"Canis Latrans", "Canis latrans", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis latrans"},
-		{"canis lupus", "Canis lupus", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis lupus"},
-		{"wolf", "Wolf", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis lupus"},
-		{"GRAY WOLF", "Gray wolf", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis lupus"},
-		{"GRAY FOX (frank)", "Gray fox", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Urocyon", "Urocyon cinereoargenteus"},
-		{"Urocyon cinereoargenteus", "Urocyon cinereoargenteus", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Urocyon", "Urocyon cinereoargenteus"},
+		{"Query", "SearchTerm", "Kingdom", "Phylum", "Class", "Order", "Family", "Genus", "Species", "Hybrid", "Parents", "Qualifier", "Authority", "Age", "Sex", "Morph", "Origin", "Breed"},
+		{"Coyote", "Coyote", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis latrans", "no", "", "", "", "", "", "", "", ""},
+		{"Canis Latrans", "Canis latrans", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis latrans", "no", "", "", "", "", "", "", "", ""},
+		{"canis lupus", "Canis lupus", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis lupus", "no", "", "", "", "", "", "", "", ""},
+		{"wolf", "Wolf", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis lupus", "no", "", "", "", "", "", "", "", ""},
+		{"GRAY WOLF", "Gray wolf", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "Canis lupus", "no", "", "", "", "", "", "", "", ""},
+		{"GRAY FOX (frank)", "Gray fox", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Urocyon", "Urocyon cinereoargenteus", "no", "", "", "", "", "", "", "", ""},
+		{"Urocyon cinereoargenteus", "Urocyon cinereoargenteus", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Urocyon", "Urocyon cinereoargenteus", "no", "", "", "", "", "", "", "", ""},
+		{"ADULT MALE RED FOX (captive bred)", "Red fox", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Vulpes", "Vulpes vulpes", "no", "", "", "", "adult", "male", "", "captive bred", ""},
+		{"Canis lupus x Canis latrans", "Canis lupus x Canis latrans", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "NA", "yes", "Canis lupus;Canis latrans", "", "", "", "", "", "", ""},
+		{"Canis sp.", "Canis", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Canis", "NA", "no", "", "sp.", "", "", "", "", "", ""},
+		{"Vulpes vulpes (Linnaeus, 1758)", "Vulpes vulpes", "Animalia", "Chordata", "Mammalia", "Carnivora", "Canidae", "Vulpes", "Vulpes vulpes", "no", "", "", "(Linnaeus, 1758)", "", "", "", "", ""},
 	}
 }
 
@@ -8,14 +8,41 @@ CREATE TABLE IF NOT EXISTS Taxonomy (
 	Genus TEXT,
 	Species TEXT,
 	Citation TEXT,
-	DB TEXT
+	DB TEXT,
+	Sources TEXT
 );
 
 CREATE TABLE IF NOT EXISTS Common (
 	ID INT,
 	Name TEXT,
+	DB TEXT,
+	Language TEXT,
 	CONSTRAINT fk_taxonomy_common FOREIGN KEY (ID) REFERENCES Taxonomy(ID) ON DELETE CASCADE ON UPDATE CASCADE
 );
 
+CREATE TABLE IF NOT EXISTS Levels (
+	ID INT,
+	Level TEXT,
+	Name TEXT,
+	CONSTRAINT fk_taxonomy_levels FOREIGN KEY (ID) REFERENCES Taxonomy(ID) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Identifiers (
+	ID INT,
+	Source TEXT,
+	Identifier TEXT,
+	CONSTRAINT fk_taxonomy_identifiers FOREIGN KEY (ID) REFERENCES Taxonomy(ID) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Cites (
+	ID INT,
+	Name TEXT,
+	Authority TEXT,
+	CONSTRAINT fk_taxonomy_cites FOREIGN KEY (ID) REFERENCES Taxonomy(ID) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
 CREATE INDEX IX_taxonomy_id ON Taxonomy (ID);
 CREATE INDEX IX_common_id ON Common (ID);
+CREATE INDEX IX_levels_id ON Levels (ID);
+CREATE INDEX IX_identifiers_id ON Identifiers (ID);
+CREATE INDEX IX_cites_id ON Cites (ID);